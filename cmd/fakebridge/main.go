@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/grandcat/zeroconf"
+)
+
+func main() {
+	var flagPort int
+	var flagDatastore string
+	var flagBridgeID string
+	var flagModelID string
+	var flagLinkButton bool
+	var flagLights string
+	var flagPublish bool
+
+	flag.IntVar(&flagPort, "port", 8080, "Port to serve the bridge's API on")
+	flag.StringVar(&flagDatastore, "datastore", "fakebridge.json", "JSON file the bridge's lights, groups, etc. are loaded from and saved to")
+	flag.StringVar(&flagBridgeID, "bridgeid", "", "Bridge ID to advertise (defaults to the one in the datastore)")
+	flag.StringVar(&flagModelID, "modelid", "", "Model ID to advertise (defaults to the one in the datastore)")
+	flag.BoolVar(&flagLinkButton, "linkbutton", false, "Press the link button on startup")
+	flag.StringVar(&flagLights, "lights", "", "JSON file to proxy lights from (lights are kept in the datastore if empty)")
+	flag.BoolVar(&flagPublish, "publish", false, "Publish light state changes to stdout as MQTT style messages")
+	flag.Parse()
+
+	store, err := fakebridge.LoadDatastore(flagDatastore)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("Datastore `%s` not found, starting with the default one", flagDatastore)
+		store = fakebridge.DefaultDatastore()
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	if flagBridgeID != "" {
+		store.Config.BridgeID = flagBridgeID
+	}
+
+	if flagModelID != "" {
+		store.Config.ModelID = flagModelID
+	}
+
+	err = store.Save(flagDatastore)
+	if err != nil {
+		log.Fatalln("Failed to save datastore:", err.Error())
+	}
+
+	bridge := fakebridge.New(store)
+
+	if flagLights != "" && flagPublish {
+		log.Fatalln("Only one of the lights and publish flags can be used.")
+	}
+
+	if flagLights != "" {
+		backend := fakebridge.FileBackend{Path: flagLights}
+		_, err := backend.Lights()
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("Lights file `%s` not found, starting with the datastore's lights", flagLights)
+			err = saveLights(flagLights, store.Lights)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		bridge.Backend = backend
+	}
+
+	// Messages go to stderr (with the log package) so they don't end up in the published stream
+	if flagPublish {
+		bridge.Backend = fakebridge.NewStreamBackend(os.Stdout, "hugh", store.Lights)
+	}
+
+	bridge.OnChange = func(store fakebridge.Datastore) {
+		err := store.Save(flagDatastore)
+		if err != nil {
+			log.Println("Failed to save datastore:", err.Error())
+		}
+	}
+
+	mdns, err := zeroconf.Register(store.Config.Name, "_hue._tcp", "local.", flagPort, store.Config.ServiceText(), nil)
+	if err != nil {
+		log.Fatalln("Failed to advertise bridge:", err.Error())
+	}
+	defer mdns.Shutdown()
+
+	server := http.Server{
+		Addr:    fmt.Sprintf(":%d", flagPort),
+		Handler: bridge,
+	}
+
+	go func() {
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		<-interrupts
+
+		server.Shutdown(context.Background())
+	}()
+
+	// Like a real bridge, schedules run on their own
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			bridge.RunSchedules()
+		}
+	}()
+
+	if flagLinkButton {
+		bridge.PressLinkButton()
+	}
+
+	go func() {
+		input := bufio.NewScanner(os.Stdin)
+		for input.Scan() {
+			bridge.PressLinkButton()
+			log.Printf("Link button pressed, new users can be created for %v", fakebridge.LinkButtonWindow)
+		}
+	}()
+
+	log.Printf("Serving bridge `%s` (ID: `%s` Model: `%s`) on port %d", store.Config.Name, store.Config.BridgeID, store.Config.ModelID, flagPort)
+	log.Println("Press the Enter Key to press the link button...")
+
+	err = server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+func saveLights(path string, lights map[string]api.Light) error {
+	data, err := json.MarshalIndent(lights, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/grandcat/zeroconf"
+)
+
+// parseAddress splits a bridge's address into its IP and port (0 if there isn't one)
+func parseAddress(address string) (net.IP, int, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip, 0, nil
+	}
+
+	host, rawPort, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, 0, usageErrorf("Invalid bridge address `%s`", address)
+	}
+
+	ip := net.ParseIP(host)
+	port, err := strconv.Atoi(rawPort)
+	if ip == nil || err != nil || port <= 0 || port > 65535 {
+		return nil, 0, usageErrorf("Invalid bridge address `%s`", address)
+	}
+
+	return ip, port, nil
+}
+
+// formatAddress formats a bridge's address the way the bridge flag expects it
+func formatAddress(bridge api.Bridge) string {
+	if bridge.Port == 0 {
+		return bridge.IP.String()
+	}
+
+	return net.JoinHostPort(bridge.IP.String(), strconv.Itoa(bridge.Port))
+}
+
+// findBridges discovers bridges on the local network
+func (app *app) findBridges() ([]api.Bridge, error) {
+	hue := app.api()
+	if hue.Browser == nil {
+		resolver, err := zeroconf.NewResolver(nil)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to initialize resolver: %w", err)
+		}
+		hue.Browser = resolver
+	}
+
+	bridges, err := hue.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bridges) == 0 {
+		return nil, errNoBridges
+	}
+
+	return bridges, nil
+}
+
+func (app *app) discover(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridges, err := app.findBridges()
+	if err != nil {
+		return err
+	}
+
+	output := listing{
+		headers: []string{"ID", "ADDRESS", "MODEL"},
+		value:   bridges,
+	}
+	for _, bridge := range bridges {
+		output.rows = append(output.rows, []string{bridge.ID, formatAddress(bridge), bridge.Model})
+	}
+
+	return app.print(output)
+}
+
+// findBridge returns the bridge named by the bridge flag, which holds either its address or its ID
+// Bridges given by ID are found with discovery. When the flag is empty, the bridge is chosen from the bridges
+// on the network.
+func (app *app) findBridge() (api.Bridge, error) {
+	if app.bridge == "" {
+		bridges, err := app.findBridges()
+		if err != nil {
+			return api.Bridge{}, err
+		}
+
+		return app.chooseBridge(bridges)
+	}
+
+	ip, port, err := parseAddress(app.bridge)
+	if err == nil {
+		return api.Bridge{API: app.api(), IP: ip, Port: port}, nil
+	}
+
+	if !bridgeID.MatchString(app.bridge) {
+		return api.Bridge{}, err
+	}
+
+	bridges, err := app.findBridges()
+	if err != nil {
+		return api.Bridge{}, err
+	}
+
+	for _, bridge := range bridges {
+		if strings.EqualFold(bridge.ID, app.bridge) {
+			return bridge, nil
+		}
+	}
+
+	return api.Bridge{}, fmt.Errorf("Bridge `%s`: %w", app.bridge, errNotFound)
+}
+
+// bridgeID matches the IDs bridges advertise (16 hexadecimal digits)
+var bridgeID = regexp.MustCompile(`^[0-9A-Fa-f]{16}$`)
+
+// chooseBridge asks which bridge to use when more than one was found
+// Without a terminal to ask on, the bridge has to be chosen with the bridge flag instead.
+func (app *app) chooseBridge(bridges []api.Bridge) (api.Bridge, error) {
+	if len(bridges) == 1 {
+		return bridges[0], nil
+	}
+
+	fmt.Fprintln(app.stderr, "More than one Hue Bridge discovered. Choose one of the following:")
+	for i, candidate := range bridges {
+		fmt.Fprintf(app.stderr, "[%d] %s (ID: `%s` Model: `%s`)\n", i, formatAddress(candidate), candidate.ID, candidate.Model)
+	}
+
+	if !isTerminal(app.stdin) {
+		return api.Bridge{}, usageErrorf("Found %d bridges (choose one by ID or address with the bridge flag)", len(bridges))
+	}
+
+	fmt.Fprint(app.stderr, "Enter selection number: ")
+	var selection int
+	fmt.Fscanln(app.stdin, &selection)
+
+	if selection < 0 || selection >= len(bridges) {
+		return api.Bridge{}, usageErrorf("Invalid selection `%d` (must be between 0 and %d)", selection, len(bridges)-1)
+	}
+
+	return bridges[selection], nil
+}
+
+// isTerminal returns true if input comes from a terminal (so there's someone to prompt)
+func isTerminal(input io.Reader) bool {
+	file, ok := input.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// pairing is what pair prints once it has a username
+type pairing struct {
+	Bridge    string `json:"bridge"`
+	ID        string `json:"id,omitempty"`
+	Username  string `json:"username"`
+	ClientKey string `json:"clientkey"`
+}
+
+// buttonPollInterval is how often pair tries to create a user while waiting for the link button
+var buttonPollInterval = time.Second
+
+func (app *app) pair(name string, args []string) error {
+	flags := app.flagSet(name)
+	var wait bool
+	var buttonTimeout time.Duration
+	flags.BoolVar(&wait, "wait-for-button", false, "Wait for the link button to be pressed instead of prompting "+
+		"(the default when input isn't a terminal)")
+	flags.DurationVar(&buttonTimeout, "button-timeout", time.Minute, "How long to wait for the link button")
+
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridge, err := app.findBridge()
+	if err != nil {
+		return err
+	}
+
+	var username string
+	if wait || !isTerminal(app.stdin) {
+		username, err = app.waitForButton(&bridge, buttonTimeout)
+	} else {
+		fmt.Fprintln(app.stderr, "Attempting to associate with bridge. Please press button on your bridge.")
+		fmt.Fprintln(app.stderr, "Press the Enter Key when ready...")
+		bufio.NewReader(app.stdin).ReadBytes('\n')
+
+		username, err = bridge.Connect()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	result := pairing{Bridge: formatAddress(bridge), ID: bridge.ID, Username: username, ClientKey: bridge.ClientKey}
+
+	return app.print(listing{
+		headers: []string{"BRIDGE", "ID", "USERNAME", "CLIENTKEY"},
+		rows:    [][]string{{result.Bridge, result.ID, result.Username, result.ClientKey}},
+		value:   result,
+	})
+}
+
+// waitForButton tries to create a user until the bridge's link button is pressed or the timeout passes
+func (app *app) waitForButton(bridge *api.Bridge, timeout time.Duration) (string, error) {
+	fmt.Fprintf(app.stderr, "Waiting up to %s for the link button on %s to be pressed...\n", timeout, formatAddress(*bridge))
+
+	deadline := time.Now().Add(timeout)
+	for {
+		username, err := bridge.Connect()
+		if !errors.Is(err, api.ErrLinkButtonNotPressed) || time.Now().Add(buttonPollInterval).After(deadline) {
+			return username, err
+		}
+
+		time.Sleep(buttonPollInterval)
+	}
+}
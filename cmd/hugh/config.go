@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// settings describes what commands run with
+type settings struct {
+	Config   string              `json:"config"`
+	Profile  string              `json:"profile"`
+	Bridge   string              `json:"bridge"`
+	Username string              `json:"username"`
+	Timeout  string              `json:"timeout"`
+	Output   string              `json:"output"`
+	Aliases  map[string][]string `json:"aliases,omitempty"`
+}
+
+func (app *app) showConfig(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	current := settings{
+		Config:   app.configPath,
+		Profile:  app.profile,
+		Bridge:   app.bridge,
+		Username: app.username,
+		Timeout:  app.timeout.String(),
+		Output:   app.output,
+		Aliases:  app.config.Aliases,
+	}
+
+	rows := [][]string{
+		{"config", current.Config},
+		{"profile", current.Profile},
+		{"bridge", current.Bridge},
+		{"username", current.Username},
+		{"timeout", current.Timeout},
+		{"output", current.Output},
+	}
+
+	aliases := []string{}
+	for alias := range current.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		rows = append(rows, []string{"alias " + alias, strings.Join(current.Aliases[alias], ", ")})
+	}
+
+	return app.print(listing{
+		headers: []string{"SETTING", "VALUE"},
+		rows:    rows,
+		value:   current,
+	})
+}
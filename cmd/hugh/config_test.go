@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/config"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	username := server.Bridge.Register("hugh#test")
+
+	file := fmt.Sprintf(`default_profile: home
+profiles:
+  home:
+    bridge: %s
+    username: %s
+    timeout: 2s
+    output: csv
+    aliases:
+      kitchen: ["Kitchen 1", "Kitchen 2"]
+  office:
+    bridge: 192.168.1.2
+    username: office
+`, server.Addr(), username)
+
+	err := ioutil.WriteFile(testConfig, []byte(file), 0600)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	defer os.Remove(testConfig)
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{
+			name:   "Test default profile",
+			args:   []string{"config"},
+			stdout: fmt.Sprintf("SETTING,VALUE\nconfig,%s\nprofile,home\nbridge,%s\nusername,%s\ntimeout,2s\noutput,csv\nalias kitchen,\"Kitchen 1, Kitchen 2\"\n", testConfig, server.Addr(), username),
+		},
+		{
+			name:   "Test environment overrides profile",
+			env:    map[string]string{config.EnvOutput: "table", config.EnvTimeout: "3s"},
+			args:   []string{"config"},
+			stdout: fmt.Sprintf("SETTING        VALUE\nconfig         %s\nprofile        home\nbridge         %s\nusername       %s\ntimeout        3s\noutput         table\nalias kitchen  Kitchen 1, Kitchen 2\n", testConfig, server.Addr(), username),
+		},
+		{
+			name:   "Test flags override environment",
+			env:    map[string]string{config.EnvOutput: "table", config.EnvUsername: "nobody"},
+			args:   []string{"config", "-output", "csv", "-username", "somebody"},
+			stdout: fmt.Sprintf("SETTING,VALUE\nconfig,%s\nprofile,home\nbridge,%s\nusername,somebody\ntimeout,2s\noutput,csv\nalias kitchen,\"Kitchen 1, Kitchen 2\"\n", testConfig, server.Addr()),
+		},
+		{
+			name:   "Test profile from environment",
+			env:    map[string]string{config.EnvProfile: "office"},
+			args:   []string{"config"},
+			stdout: fmt.Sprintf("SETTING   VALUE\nconfig    %s\nprofile   office\nbridge    192.168.1.2\nusername  office\ntimeout   10s\noutput    table\n", testConfig),
+		},
+		{
+			name:   "Test profile flag overrides environment",
+			env:    map[string]string{config.EnvProfile: "office"},
+			args:   []string{"-profile", "home", "config"},
+			stdout: fmt.Sprintf("SETTING,VALUE\nconfig,%s\nprofile,home\nbridge,%s\nusername,%s\ntimeout,2s\noutput,csv\nalias kitchen,\"Kitchen 1, Kitchen 2\"\n", testConfig, server.Addr(), username),
+		},
+		{
+			name: "Test unknown profile",
+			args: []string{"-profile", "cabin", "config"},
+			code: exitUsage,
+		},
+		{
+			name: "Test invalid timeout",
+			env:  map[string]string{config.EnvTimeout: "soon"},
+			args: []string{"config"},
+			code: exitUsage,
+		},
+		{
+			name:   "Test alias",
+			args:   []string{"lights", "on", "kitchen"},
+			stdout: "ID,NAME,RESULT\n1,Kitchen 1,ok\n2,Kitchen 2,ok\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for env, value := range tt.env {
+				os.Setenv(env, value)
+				defer os.Unsetenv(env)
+			}
+
+			code, stdout, stderr := hugh(t, server, "", tt.args...)
+			if code != tt.code {
+				t.Fatalf("Expected exit code %d but got %d (%s)", tt.code, code, stderr)
+			}
+
+			if tt.stdout != "" && stdout != tt.stdout {
+				t.Errorf("Expected output:\n%s\nbut got:\n%s", tt.stdout, stdout)
+			}
+		})
+	}
+}
+
+func TestConnectClientKey(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	app := &app{
+		bridge:   server.Addr().String(),
+		username: "home",
+		timeout:  time.Second,
+		config:   config.Profile{Username: "home", ClientKey: "0123456789abcdef"},
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if bridge.ClientKey != "0123456789abcdef" {
+		t.Errorf("Expected the profile's client key but got `%s`", bridge.ClientKey)
+	}
+
+	// The key belongs to the profile's username
+	app.username = "somebody"
+	bridge, err = app.connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if bridge.ClientKey != "" {
+		t.Errorf("Expected no client key but got `%s`", bridge.ClientKey)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// Exit codes
+const (
+	exitOK           = 0
+	exitFailure      = 1 // Anything not covered below
+	exitUsage        = 2 // Unknown command, invalid flags or arguments
+	exitUnreachable  = 3 // The bridge couldn't be reached or no bridges were found
+	exitUnauthorized = 4 // The username was rejected or the link button wasn't pressed
+	exitNotFound     = 5 // A light, group, scene, etc. doesn't exist
+)
+
+// errNoBridges is returned when discovery doesn't find any bridges
+var errNoBridges = errors.New("No Hue bridges found")
+
+// errNotFound is returned when a light, group, scene, etc. doesn't exist
+var errNotFound = errors.New("Not found")
+
+// usageError is returned when a command is used incorrectly
+// An empty message means the problem was already reported (by the flag package for example).
+type usageError struct {
+	message string
+}
+
+func (err *usageError) Error() string {
+	return err.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// exitCode returns the exit code describing an error
+func exitCode(err error) int {
+	var usageErr *usageError
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, api.ErrUnauthorized), errors.Is(err, api.ErrLinkButtonNotPressed):
+		return exitUnauthorized
+	case errors.Is(err, errNotFound), errors.Is(err, api.ErrResourceNotAvailable):
+		return exitNotFound
+	case errors.Is(err, errNoBridges), errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return exitUnreachable
+	}
+
+	return exitFailure
+}
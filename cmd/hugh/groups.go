@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// sortedGroups retrieves a bridge's groups ordered by ID
+func sortedGroups(bridge *api.Bridge) ([]api.Group, error) {
+	groups, err := bridge.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return lessID(groups[i].ID, groups[j].ID)
+	})
+
+	return groups, nil
+}
+
+// resolveGroup finds the group with an ID or name (ignoring case)
+func resolveGroup(groups []api.Group, arg string) (api.Group, error) {
+	for _, group := range groups {
+		if group.ID == arg || strings.EqualFold(group.Name, arg) {
+			return group, nil
+		}
+	}
+
+	return api.Group{}, fmt.Errorf("Group `%s`: %w", arg, errNotFound)
+}
+
+func (app *app) listGroups(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	groups, err := sortedGroups(bridge)
+	if err != nil {
+		return err
+	}
+
+	output := listing{
+		headers: []string{"ID", "NAME", "TYPE", "CLASS", "LIGHTS", "ANY ON", "ALL ON"},
+		value:   map[string]api.Group{},
+	}
+	for _, group := range groups {
+		output.value.(map[string]api.Group)[group.ID] = group
+		output.rows = append(output.rows, []string{
+			group.ID,
+			group.Name,
+			group.Type,
+			group.Class,
+			strings.Join(group.Lights, ","),
+			strconv.FormatBool(group.State.AnyOn),
+			strconv.FormatBool(group.State.AllOn),
+		})
+	}
+
+	return app.print(output)
+}
+
+func (app *app) groupsOn(name string, args []string) error {
+	return app.switchGroups(name, args, true)
+}
+
+func (app *app) groupsOff(name string, args []string) error {
+	return app.switchGroups(name, args, false)
+}
+
+// switchGroups turns every light in groups on or off
+func (app *app) switchGroups(name string, args []string, on bool) error {
+	flags := app.flagSet(name)
+	positional, err := app.parse(flags, args, "<group>...")
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		return usageErrorf("At least one group must be given")
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	groups, err := sortedGroups(bridge)
+	if err != nil {
+		return err
+	}
+
+	targets := []api.Group{}
+	for _, arg := range positional {
+		group, err := resolveGroup(groups, arg)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, group)
+	}
+
+	for _, group := range targets {
+		err := group.SetStateContext(context.Background(), api.LightStateUpdate{On: &on})
+		if err != nil {
+			return fmt.Errorf("Failed to update group `%s`: %w", group.Name, err)
+		}
+	}
+
+	return nil
+}
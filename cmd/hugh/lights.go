@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// sortedLights retrieves a bridge's lights ordered by ID
+func sortedLights(bridge *api.Bridge) ([]api.Light, error) {
+	lights, err := bridge.GetLights()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(lights, func(i, j int) bool {
+		return lessID(lights[i].ID, lights[j].ID)
+	})
+
+	return lights, nil
+}
+
+// lessID orders IDs numerically when they're numbers (like the bridge's) and alphabetically otherwise
+func lessID(first, second string) bool {
+	firstNumber, firstErr := strconv.Atoi(first)
+	secondNumber, secondErr := strconv.Atoi(second)
+	if firstErr == nil && secondErr == nil {
+		return firstNumber < secondNumber
+	}
+
+	return first < second
+}
+
+// resolveLights finds the lights matching each argument by ID, name or glob pattern (`Kitchen*` for example)
+// Names and patterns ignore case. Every light is returned once, in the order the arguments were given.
+func resolveLights(lights []api.Light, args []string) ([]api.Light, error) {
+	if len(args) == 0 {
+		return nil, usageErrorf("At least one light must be given")
+	}
+
+	resolved := []api.Light{}
+	seen := map[string]bool{}
+	for _, arg := range args {
+		pattern := strings.ToLower(arg)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, usageErrorf("Invalid pattern `%s`", arg)
+		}
+
+		found := false
+		for _, light := range lights {
+			matched, _ := path.Match(pattern, strings.ToLower(light.Name))
+			if light.ID != arg && !matched {
+				continue
+			}
+
+			found = true
+			if !seen[light.ID] {
+				seen[light.ID] = true
+				resolved = append(resolved, light)
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Light `%s`: %w", arg, errNotFound)
+		}
+	}
+
+	return resolved, nil
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func (app *app) listLights(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	lights, err := sortedLights(bridge)
+	if err != nil {
+		return err
+	}
+
+	output := listing{
+		headers: []string{"ID", "NAME", "TYPE", "STATE", "BRIGHTNESS", "REACHABLE"},
+		value:   map[string]api.Light{},
+	}
+	for _, light := range lights {
+		output.value.(map[string]api.Light)[light.ID] = light
+		output.rows = append(output.rows, []string{
+			light.ID,
+			light.Name,
+			light.Type,
+			onOff(light.State.On),
+			strconv.Itoa(int(light.State.Brightness)),
+			strconv.FormatBool(light.State.Reachable),
+		})
+	}
+
+	return app.print(output)
+}
+
+func (app *app) lightsOn(name string, args []string) error {
+	return app.switchLights(name, args, true)
+}
+
+func (app *app) lightsOff(name string, args []string) error {
+	return app.switchLights(name, args, false)
+}
+
+// switchLights turns lights on or off
+func (app *app) switchLights(name string, args []string, on bool) error {
+	flags := app.flagSet(name)
+	var transition time.Duration
+	flags.DurationVar(&transition, "transition", 0, "How long the change takes (the bridge's default if not set)")
+
+	positional, err := app.parse(flags, args, "<light>...")
+	if err != nil {
+		return err
+	}
+
+	change := lightChange{on: &on}
+	if visited(flags)["transition"] {
+		change.transition = &transition
+	}
+
+	return app.updateLights(positional, change)
+}
+
+func (app *app) setLights(name string, args []string) error {
+	flags := app.flagSet(name)
+	var on, off bool
+	var brightness, color, temperature string
+	var transition time.Duration
+	flags.BoolVar(&on, "on", false, "Turn the lights on")
+	flags.BoolVar(&off, "off", false, "Turn the lights off")
+	flags.StringVar(&brightness, "bri", "", "Brightness from 1 to 254 or as a percentage (`50%` for example)")
+	flags.StringVar(&color, "color", "", "Color as a hex RGB value (`#ffaa00` for example)")
+	flags.StringVar(&temperature, "ct", "", "Color temperature in mireds or Kelvin (`2700K` for example)")
+	flags.DurationVar(&transition, "transition", 0, "How long the change takes (the bridge's default if not set)")
+
+	positional, err := app.parse(flags, args, "<light>...")
+	if err != nil {
+		return err
+	}
+
+	if on && off {
+		return usageErrorf("Only one of the on and off flags can be used")
+	}
+
+	set := visited(flags)
+	if !set["on"] && !set["off"] && !set["bri"] && !set["color"] && !set["ct"] {
+		return usageErrorf("Nothing to change (see `%s -h`)", name)
+	}
+
+	change := lightChange{}
+	if on || off {
+		change.on = &on
+	}
+
+	if set["bri"] {
+		value, err := parseBrightness(brightness)
+		if err != nil {
+			return err
+		}
+		change.brightness = &value
+	}
+
+	if set["color"] {
+		coords, err := parseColor(color)
+		if err != nil {
+			return err
+		}
+		change.color = &coords
+	}
+
+	if set["ct"] {
+		value, err := parseTemperature(temperature)
+		if err != nil {
+			return err
+		}
+		change.temperature = &value
+	}
+
+	if set["transition"] {
+		change.transition = &transition
+	}
+
+	return app.updateLights(positional, change)
+}
+
+// parseBrightness parses a brightness given as a value the bridge accepts or as a percentage
+// Percentages are scaled to the bridge's range with anything above 0% being at least the minimum brightness.
+func parseBrightness(value string) (int, error) {
+	if strings.HasSuffix(value, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percentage <= 0 || percentage > 100 {
+			return 0, usageErrorf("Invalid brightness `%s` (percentages must be above 0%% and at most 100%%)", value)
+		}
+
+		brightness := int(math.Round(percentage / 100 * api.MaximumBrightness))
+		if brightness < api.MinimumBrightness {
+			brightness = api.MinimumBrightness
+		}
+
+		return brightness, nil
+	}
+
+	brightness, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, usageErrorf("Invalid brightness `%s`", value)
+	}
+
+	return brightness, nil
+}
+
+// parseColor parses a hex RGB color (the leading # is optional)
+func parseColor(value string) (api.CIECoord, error) {
+	hex := strings.TrimPrefix(value, "#")
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return api.CIECoord{}, usageErrorf("Invalid color `%s` (must be a hex RGB value like #ffaa00)", value)
+	}
+
+	return api.RGBToCIE(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+}
+
+// colorTemperature is a color temperature given in mireds or in Kelvin
+type colorTemperature struct {
+	mireds int
+	kelvin uint // Only set if the temperature was given in Kelvin
+}
+
+// parseTemperature parses a color temperature in mireds or in Kelvin (with a K suffix)
+func parseTemperature(value string) (colorTemperature, error) {
+	if kelvin := strings.TrimSuffix(strings.TrimSuffix(value, "K"), "k"); kelvin != value {
+		temperature, err := strconv.ParseUint(kelvin, 10, 32)
+		if err != nil || temperature == 0 {
+			return colorTemperature{}, usageErrorf("Invalid color temperature `%s`", value)
+		}
+
+		return colorTemperature{mireds: int(api.KelvinToMired(uint(temperature))), kelvin: uint(temperature)}, nil
+	}
+
+	temperature, err := strconv.Atoi(value)
+	if err != nil {
+		return colorTemperature{}, usageErrorf("Invalid color temperature `%s`", value)
+	}
+
+	return colorTemperature{mireds: temperature}, nil
+}
+
+// set adds the temperature to the state sent to a light (nil to validate it against standard Hue bulbs)
+// Like api.Light.SetColorTemperatureKelvin, temperatures given in Kelvin are clamped to the light's range
+// and color lights without color temperature support are sent an approximation in the CIE color space.
+// Temperatures given in mireds are sent as is.
+func (temperature colorTemperature) set(builder *api.StateBuilder, light *api.Light) {
+	colorOnly := light != nil && !light.Supports(api.FeatureColorTemperature) && light.Supports(api.FeatureColor)
+	if colorOnly && (temperature.kelvin != 0 || temperature.mireds > 0) {
+		kelvin := temperature.kelvin
+		if kelvin == 0 {
+			kelvin = api.MiredToKelvin(uint(temperature.mireds))
+		}
+
+		coords := api.KelvinToCIE(kelvin)
+		builder.CIECoords(coords[0], coords[1])
+		return
+	}
+
+	if temperature.kelvin != 0 {
+		builder.Temperature(int(light.TemperatureRange().ClampKelvin(temperature.kelvin)))
+		return
+	}
+
+	builder.Temperature(temperature.mireds)
+}
+
+// lightChange is a change requested on the command line
+// Attributes that aren't set are left alone.
+type lightChange struct {
+	on          *bool
+	brightness  *int
+	color       *api.CIECoord
+	temperature *colorTemperature
+	transition  *time.Duration
+}
+
+// builder creates the state sent to a light (or every attribute of the change if the light is nil)
+// A color and a color temperature can be requested together to change a mix of lights: color lights are
+// sent the color and lights that only support color temperatures are sent the temperature.
+func (change lightChange) builder(light *api.Light) *api.StateBuilder {
+	builder := api.NewStateBuilder()
+	if change.on != nil {
+		builder.On(*change.on)
+	}
+
+	if change.brightness != nil {
+		builder.Brightness(*change.brightness)
+	}
+
+	color := change.color != nil
+	temperature := change.temperature != nil
+	if light != nil && color && temperature {
+		color = light.Supports(api.FeatureColor) || !light.Supports(api.FeatureColorTemperature)
+		temperature = !color
+	}
+
+	if color {
+		builder.CIECoords(change.color[0], change.color[1])
+	}
+
+	if temperature {
+		change.temperature.set(builder, light)
+	}
+
+	if change.transition != nil {
+		builder.TransitionTime(*change.transition)
+	}
+
+	return builder
+}
+
+// lightResult is the outcome of changing a light
+type lightResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// updateLights applies a change to every light named by the arguments and prints the result for each light
+// Invalid changes are reported before any light is changed. Lights that fail to update don't stop the
+// others from being changed but the first failure is returned.
+func (app *app) updateLights(args []string, change lightChange) error {
+	if _, err := change.builder(nil).Build(nil); err != nil {
+		return &usageError{message: err.Error()}
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	lights, err := sortedLights(bridge)
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveLights(lights, app.config.ExpandAliases(args))
+	if err != nil {
+		return err
+	}
+
+	var failure error
+	results := []lightResult{}
+	output := listing{headers: []string{"ID", "NAME", "RESULT"}}
+	for _, light := range targets {
+		update, err := change.builder(&light).Build(&light)
+		if err == nil {
+			err = light.SetStateContext(context.Background(), update)
+		}
+
+		result := lightResult{ID: light.ID, Name: light.Name, Success: err == nil}
+		status := "ok"
+		if err != nil {
+			result.Error = err.Error()
+			status = result.Error
+			if failure == nil {
+				failure = fmt.Errorf("Failed to update light `%s`: %w", light.Name, err)
+			}
+		}
+
+		results = append(results, result)
+		output.rows = append(output.rows, []string{light.ID, light.Name, status})
+	}
+
+	output.value = results
+	if err := app.print(output); err != nil {
+		return err
+	}
+
+	return failure
+}
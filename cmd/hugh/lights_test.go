@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveLights(t *testing.T) {
+	lights := []api.Light{
+		{ID: "1", Name: "Kitchen 1"},
+		{ID: "2", Name: "Kitchen 2"},
+		{ID: "3", Name: "Hallway"},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		err      error
+	}{
+		{name: "Test ID", args: []string{"2"}, expected: []string{"2"}},
+		{name: "Test name ignores case", args: []string{"hallway"}, expected: []string{"3"}},
+		{name: "Test glob", args: []string{"Kitchen*"}, expected: []string{"1", "2"}},
+		{name: "Test glob ignores case", args: []string{"*WAY"}, expected: []string{"3"}},
+		{name: "Test duplicates are removed", args: []string{"3", "Kitchen ?", "1"}, expected: []string{"3", "1", "2"}},
+		{name: "Test unknown light", args: []string{"1", "Garage"}, err: errNotFound},
+		{name: "Test glob without matches", args: []string{"Garage*"}, err: errNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := resolveLights(lights, tt.args)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v but got %v", tt.err, err)
+			}
+
+			ids := []string{}
+			for _, light := range resolved {
+				ids = append(ids, light.ID)
+			}
+
+			if tt.err == nil {
+				if diff := cmp.Diff(tt.expected, ids); diff != "" {
+					t.Errorf("Unexpected lights (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		parse    func(string) (interface{}, error)
+		value    string
+		expected interface{}
+		invalid  bool
+	}{
+		{name: "Test brightness", parse: brightnessValue, value: "200", expected: 200},
+		{name: "Test brightness percentage", parse: brightnessValue, value: "50%", expected: 127},
+		{name: "Test full brightness", parse: brightnessValue, value: "100%", expected: 254},
+		{name: "Test small percentage is the minimum brightness", parse: brightnessValue, value: "0.1%", expected: 1},
+		{name: "Test zero percent", parse: brightnessValue, value: "0%", invalid: true},
+		{name: "Test percentage above 100", parse: brightnessValue, value: "150%", invalid: true},
+		{name: "Test invalid brightness", parse: brightnessValue, value: "bright", invalid: true},
+		{name: "Test color", parse: colorValue, value: "#ff0000", expected: api.CIECoord{0.7006, 0.2993}},
+		{name: "Test color without #", parse: colorValue, value: "FFFFFF", expected: api.CIECoord{0.3227, 0.329}},
+		{name: "Test short color", parse: colorValue, value: "#fa0", invalid: true},
+		{name: "Test invalid color", parse: colorValue, value: "#orange", invalid: true},
+		{name: "Test mireds", parse: temperatureValue, value: "366", expected: 366},
+		{name: "Test Kelvin", parse: temperatureValue, value: "2700K", expected: 370},
+		{name: "Test lowercase Kelvin", parse: temperatureValue, value: "6500k", expected: 154},
+		{name: "Test zero Kelvin", parse: temperatureValue, value: "0K", invalid: true},
+		{name: "Test invalid temperature", parse: temperatureValue, value: "warm", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.value)
+			if tt.invalid {
+				var usageErr *usageError
+				if !errors.As(err, &usageErr) {
+					t.Errorf("Expected a usage error but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Unexpected value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func brightnessValue(value string) (interface{}, error) {
+	return parseBrightness(value)
+}
+
+func colorValue(value string) (interface{}, error) {
+	return parseColor(value)
+}
+
+func temperatureValue(value string) (interface{}, error) {
+	temperature, err := parseTemperature(value)
+	return temperature.mireds, err
+}
+
+func TestSetLights(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	username := server.Bridge.Register("hugh#test")
+
+	code, stdout, stderr := hugh(t, server, "", "-bridge", server.Addr().String(), "-username", username,
+		"lights", "set", "Kitchen*", "--on", "--bri", "50%", "--color", "#ffaa00", "--ct", "2700K", "--transition", "2s")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d but got %d (%s)", exitOK, code, stderr)
+	}
+
+	expected := "ID  NAME       RESULT\n1   Kitchen 1  ok\n2   Kitchen 2  ok\n"
+	if stdout != expected {
+		t.Errorf("Expected output:\n%s\nbut got:\n%s", expected, stdout)
+	}
+
+	lights := server.Bridge.Datastore().Lights
+	color, temperature := lights["1"].State, lights["2"].State
+	if !color.On || color.Brightness != 127 || color.ColorMode != "xy" || color.CIECoords != (api.CIECoord{0.5553, 0.4224}) {
+		t.Errorf("Expected the color light to be set to the color but got %+v", color)
+	}
+
+	if !temperature.On || temperature.Brightness != 127 || temperature.ColorMode != "ct" || temperature.Temperature != 370 {
+		t.Errorf("Expected the color temperature light to be set to the temperature but got %+v", temperature)
+	}
+
+	// Lights that fail don't stop the others from changing
+	code, stdout, _ = hugh(t, server, "", "-bridge", server.Addr().String(), "-username", username,
+		"lights", "set", "Hallway", "Kitchen 1", "--ct", "2700K", "--output", "json")
+	if code != exitFailure {
+		t.Errorf("Expected exit code %d but got %d", exitFailure, code)
+	}
+
+	if !strings.Contains(stdout, `"success": false`) || !strings.Contains(stdout, `"success": true`) {
+		t.Errorf("Expected a failure and a success but got `%s`", stdout)
+	}
+
+	if server.Bridge.Datastore().Lights["1"].State.Temperature != 370 {
+		t.Errorf("Expected the color light to be changed but got %+v", server.Bridge.Datastore().Lights["1"].State)
+	}
+}
+
+func TestSetTemperature(t *testing.T) {
+	store := fakebridge.DefaultDatastore()
+	light := store.Lights["1"]
+	light.Type = api.LightTypeColor
+	light.Capabilities.Control.TemperatureRange = api.LightTemperatureRange{}
+	store.Lights["1"] = light
+
+	server := fakebridge.NewServer(store)
+	defer server.Close()
+
+	username := server.Bridge.Register("hugh#test")
+
+	// Kitchen 2 only goes down to 454 (about 2200K)
+	code, _, stderr := hugh(t, server, "", "-bridge", server.Addr().String(), "-username", username,
+		"lights", "set", "Kitchen*", "--on", "--ct", "2000K")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d but got %d (%s)", exitOK, code, stderr)
+	}
+
+	lights := server.Bridge.Datastore().Lights
+	color, temperature := lights["1"].State, lights["2"].State
+	if color.ColorMode != "xy" || color.CIECoords != api.KelvinToCIE(2000) {
+		t.Errorf("Expected the color light to be set to an approximation of the temperature but got %+v", color)
+	}
+
+	if temperature.ColorMode != "ct" || temperature.Temperature != 454 {
+		t.Errorf("Expected the color temperature light to be set to its warmest temperature but got %+v", temperature)
+	}
+
+	// Mireds aren't clamped
+	code, _, _ = hugh(t, server, "", "-bridge", server.Addr().String(), "-username", username,
+		"lights", "set", "Kitchen 2", "--ct", "500")
+	if code != exitFailure {
+		t.Errorf("Expected exit code %d but got %d", exitFailure, code)
+	}
+}
@@ -0,0 +1,321 @@
+// Command hugh controls Phillips Hue bridges from the command line
+// Every command accepts the global flags (profile, bridge, username, timeout and output) before or after its
+// own arguments. Listings are printed to stdout as a table, JSON, YAML or CSV and everything else (prompts,
+// progress, errors) to stderr so the output can be piped into other programs. See exit.go for the exit codes.
+//
+// Settings that aren't given as flags are read from the environment and then from a profile in the
+// configuration file (see the config package).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/config"
+)
+
+// app holds the global flags and the streams commands read from and write to
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// Global settings (see configure for where they come from)
+	profile  string
+	bridge   string
+	username string
+	timeout  time.Duration
+	output   string
+
+	// The global flags that were given
+	set map[string]bool
+
+	// The profile the settings were read from and the configuration file it's in
+	config     config.Profile
+	configPath string
+
+	// Finds bridges on the network (an mDNS resolver if nil)
+	browser api.MulticastBrowser
+}
+
+// command is a subcommand of hugh
+// Commands either run something or group other commands (`lights list`, `lights on`, etc.).
+type command struct {
+	name        string
+	args        string // Describes the positional arguments in usage messages
+	summary     string
+	run         func(app *app, name string, args []string) error
+	subcommands []command
+}
+
+// commands returns every command hugh understands
+func commands() []command {
+	return []command{
+		{name: "discover", summary: "Find bridges on the local network", run: (*app).discover},
+		{name: "pair", summary: "Create a username on a bridge (by pressing its link button)", run: (*app).pair},
+		{name: "lights", summary: "List and control lights", subcommands: []command{
+			{name: "list", summary: "List lights", run: (*app).listLights},
+			{name: "on", args: "<light>...", summary: "Turn lights on", run: (*app).lightsOn},
+			{name: "off", args: "<light>...", summary: "Turn lights off", run: (*app).lightsOff},
+			{name: "set", args: "<light>...", summary: "Change the state of lights", run: (*app).setLights},
+		}},
+		{name: "groups", summary: "List and control rooms, zones and other groups", subcommands: []command{
+			{name: "list", summary: "List groups", run: (*app).listGroups},
+			{name: "on", args: "<group>...", summary: "Turn every light in groups on", run: (*app).groupsOn},
+			{name: "off", args: "<group>...", summary: "Turn every light in groups off", run: (*app).groupsOff},
+		}},
+		{name: "scenes", summary: "List and recall scenes", subcommands: []command{
+			{name: "list", summary: "List scenes", run: (*app).listScenes},
+			{name: "recall", args: "<scene> <group>", summary: "Recall a scene for a group", run: (*app).recallScene},
+		}},
+		{name: "sensors", summary: "List sensors", subcommands: []command{
+			{name: "list", summary: "List sensors", run: (*app).listSensors},
+		}},
+		{name: "config", summary: "Show the settings commands run with", run: (*app).showConfig},
+		{name: "tui", summary: "Browse and control lights, rooms, zones and scenes interactively", run: (*app).tui},
+	}
+}
+
+func main() {
+	app := &app{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	os.Exit(app.run(os.Args[1:]))
+}
+
+// run runs the command described by the arguments and returns the exit code
+func (app *app) run(args []string) int {
+	app.timeout = config.DefaultTimeout
+	app.output = outputTable
+	app.set = map[string]bool{}
+
+	// Keep stdout for results (the api package logs progress)
+	log.SetOutput(app.stderr)
+
+	flags := app.flagSet("hugh")
+	flags.Usage = func() {
+		app.usage("hugh", commands())
+	}
+
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+
+	// The flag package already reported the problem
+	if err != nil {
+		err = &usageError{}
+	}
+
+	for name := range visited(flags) {
+		app.set[name] = true
+	}
+
+	if err == nil {
+		err = app.dispatch("hugh", commands(), flags.Args())
+	}
+
+	if err != nil {
+		if _, ok := err.(*usageError); !ok || err.Error() != "" {
+			fmt.Fprintln(app.stderr, "Error:", err)
+		}
+	}
+
+	return exitCode(err)
+}
+
+// dispatch finds the command named by the first argument and runs it with the rest
+func (app *app) dispatch(path string, available []command, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		app.usage(path, available)
+		if len(args) == 0 {
+			return &usageError{}
+		}
+
+		return nil
+	}
+
+	for _, cmd := range available {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		name := path + " " + cmd.name
+		if cmd.subcommands != nil {
+			return app.dispatch(name, cmd.subcommands, args[1:])
+		}
+
+		return cmd.run(app, name, args[1:])
+	}
+
+	return usageErrorf("Unknown command `%s` (see `%s help`)", args[0], path)
+}
+
+// usage describes the available commands
+func (app *app) usage(path string, available []command) {
+	fmt.Fprintf(app.stderr, "Usage: %s [flags] <command>\n\nCommands:\n", path)
+	for _, cmd := range available {
+		fmt.Fprintf(app.stderr, "  %-20s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+
+	fmt.Fprintln(app.stderr, "\nGlobal flags:")
+	app.flagSet(path).PrintDefaults()
+}
+
+// flagSet creates a flag set holding the global flags
+// The flags default to their current values so global flags given before a command aren't reset.
+func (app *app) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(app.stderr)
+
+	flags.StringVar(&app.profile, "profile", app.profile, "Profile in the configuration file to use")
+	flags.StringVar(&app.bridge, "bridge", app.bridge, "Address (`host[:port]`) or ID of the bridge")
+	flags.StringVar(&app.username, "username", app.username, "Username to send requests as (see the pair command)")
+	flags.DurationVar(&app.timeout, "timeout", app.timeout, "How long to wait for requests and discovery")
+	flags.StringVar(&app.output, "output", app.output, fmt.Sprintf("Output format (%s)", strings.Join(outputFormats, ", ")))
+
+	return flags
+}
+
+// parse parses a command's flags (which may be mixed with its positional arguments)
+// The positional arguments are returned.
+func (app *app) parse(flags *flag.FlagSet, args []string, usage string) ([]string, error) {
+	flags.Usage = func() {
+		fmt.Fprintf(app.stderr, "Usage: %s [flags] %s\n\nFlags:\n", flags.Name(), usage)
+		flags.PrintDefaults()
+	}
+
+	positional := []string{}
+	for {
+		err := flags.Parse(args)
+		if err == flag.ErrHelp {
+			return nil, &usageError{}
+		}
+
+		if err != nil {
+			return nil, usageErrorf("")
+		}
+
+		for name := range visited(flags) {
+			app.set[name] = true
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	err := app.configure()
+	if err != nil {
+		return nil, err
+	}
+
+	if !validOutput(app.output) {
+		return nil, usageErrorf("Unknown output format `%s` (must be one of %s)", app.output, strings.Join(outputFormats, ", "))
+	}
+
+	return positional, nil
+}
+
+// visited returns the names of the flags that were set
+func visited(flags *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return set
+}
+
+// configure fills in the global settings that weren't given as flags
+// Settings come from the environment, then the profile in the configuration file, then the defaults.
+func (app *app) configure() error {
+	file, path, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	profile, err := file.Profile(app.profile)
+	if err != nil {
+		return usageErrorf("%s", err)
+	}
+
+	app.profile = file.ProfileName(app.profile)
+	app.config = profile
+	app.configPath = path
+
+	setting := func(flag, env, configured string, value *string) {
+		if app.set[flag] {
+			return
+		}
+
+		if fromEnv := os.Getenv(env); fromEnv != "" {
+			*value = fromEnv
+		} else if configured != "" {
+			*value = configured
+		}
+	}
+
+	setting("bridge", config.EnvBridge, profile.Bridge, &app.bridge)
+	setting("username", config.EnvUsername, profile.Username, &app.username)
+	setting("output", config.EnvOutput, profile.Output, &app.output)
+
+	if !app.set["timeout"] {
+		if fromEnv := os.Getenv(config.EnvTimeout); fromEnv != "" {
+			app.timeout, err = time.ParseDuration(fromEnv)
+			if err != nil {
+				return usageErrorf("Invalid timeout `%s` in %s", fromEnv, config.EnvTimeout)
+			}
+		} else if profile.Timeout > 0 {
+			app.timeout = profile.Timeout
+		}
+	}
+
+	return nil
+}
+
+// api creates the API requests are sent with
+func (app *app) api() *api.API {
+	hue := config.Profile{Timeout: app.timeout, RateLimits: app.config.RateLimits}.API()
+	hue.Browser = app.browser
+
+	return hue
+}
+
+// connect returns the bridge given by the global flags
+func (app *app) connect() (*api.Bridge, error) {
+	if app.bridge == "" {
+		return nil, usageErrorf("A bridge must be supplied with the bridge flag")
+	}
+
+	if app.username == "" {
+		return nil, usageErrorf("A username must be supplied with the username flag (see `hugh pair`)")
+	}
+
+	bridge, err := app.findBridge()
+	if err != nil {
+		return nil, err
+	}
+
+	bridge.Username = app.username
+
+	// Client keys are issued along with a username so the profile's key is only used with its username
+	if app.username == app.config.Username {
+		bridge.ClientKey = app.config.ClientKey
+	}
+
+	return &bridge, nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/config"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/grandcat/zeroconf"
+)
+
+// testConfig is where the configuration file is read from during tests
+var testConfig string
+
+func TestMain(m *testing.M) {
+	// Keep the user's configuration and environment out of the tests
+	dir, err := ioutil.TempDir("", "hugh")
+	if err != nil {
+		panic(err)
+	}
+
+	testConfig = filepath.Join(dir, "config.yaml")
+	os.Setenv(config.EnvConfig, testConfig)
+	for _, env := range []string{config.EnvProfile, config.EnvBridge, config.EnvUsername, config.EnvTimeout, config.EnvOutput} {
+		os.Unsetenv(env)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// hugh runs a command against a fake bridge and returns its exit code, stdout and stderr
+func hugh(t *testing.T, server *fakebridge.Server, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	app := &app{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+	}
+
+	if server != nil {
+		app.browser = &fakebridge.Browser{Entries: []*zeroconf.ServiceEntry{server.ServiceEntry()}}
+	}
+
+	code := app.run(args)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	username := server.Bridge.Register("hugh#test")
+	global := []string{"-bridge", server.Addr().String(), "-username", username, "-timeout", "1s"}
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	closed := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		check  func(t *testing.T, store fakebridge.Datastore)
+
+		// Text that should appear exactly once on stderr
+		reported string
+	}{
+		{
+			name: "Test no command",
+			args: []string{},
+			code: exitUsage,
+		},
+		{
+			name: "Test unknown command",
+			args: []string{"lamps"},
+			code: exitUsage,
+		},
+		{
+			name:     "Test unknown global flag",
+			args:     []string{"-bogus", "lights", "list"},
+			code:     exitUsage,
+			reported: "-bogus",
+		},
+		{
+			name:     "Test unknown command flag",
+			args:     []string{"lights", "list", "-bogus"},
+			code:     exitUsage,
+			reported: "-bogus",
+		},
+		{
+			name: "Test help",
+			args: []string{"lights", "help"},
+			code: exitOK,
+		},
+		{
+			name: "Test missing bridge",
+			args: []string{"lights", "list"},
+			code: exitUsage,
+		},
+		{
+			name: "Test unknown output format",
+			args: append([]string{"lights", "list", "-output", "xml"}, global...),
+			code: exitUsage,
+		},
+		{
+			name:   "Test list lights",
+			args:   append([]string{"lights", "list"}, global...),
+			stdout: "ID  NAME       TYPE                     STATE  BRIGHTNESS  REACHABLE\n1   Kitchen 1  Extended color light     off    254         true\n2   Kitchen 2  Color temperature light  off    254         true\n3   Hallway    Dimmable light           off    254         true\n",
+		},
+		{
+			name: "Test flags after arguments",
+			args: append([]string{"lights", "on", "Hallway", "-transition", "1s"}, global...),
+			check: func(t *testing.T, store fakebridge.Datastore) {
+				if !store.Lights["3"].State.On {
+					t.Error("Expected the hallway light to be on")
+				}
+			},
+		},
+		{
+			name: "Test set",
+			args: append(global, "lights", "set", "1", "2", "-on", "-bri", "100", "-ct", "300"),
+			check: func(t *testing.T, store fakebridge.Datastore) {
+				for _, id := range []string{"1", "2"} {
+					state := store.Lights[id].State
+					if !state.On || state.Brightness != 100 || state.Temperature != 300 {
+						t.Errorf("Expected light %s to be changed but got %+v", id, state)
+					}
+				}
+			},
+		},
+		{
+			name: "Test invalid state",
+			args: append(global, "lights", "set", "1", "-bri", "0"),
+			code: exitUsage,
+		},
+		{
+			name: "Test unknown light",
+			args: append(global, "lights", "off", "Garage"),
+			code: exitNotFound,
+		},
+		{
+			name: "Test groups off",
+			args: append(global, "groups", "off", "kitchen"),
+			check: func(t *testing.T, store fakebridge.Datastore) {
+				if store.Lights["1"].State.On || store.Lights["2"].State.On || !store.Lights["3"].State.On {
+					t.Errorf("Expected only the kitchen lights to be off but got %+v", store.Lights)
+				}
+			},
+		},
+		{
+			name: "Test scene recall",
+			args: append(global, "scenes", "recall", "relax", "Kitchen"),
+			check: func(t *testing.T, store fakebridge.Datastore) {
+				if store.Lights["2"].State.Temperature != 447 {
+					t.Errorf("Expected the scene to be recalled but got %+v", store.Lights["2"].State)
+				}
+			},
+		},
+		{
+			name:   "Test config",
+			args:   []string{"config", "-bridge", "192.168.1.2", "-output", "json"},
+			stdout: "{\n  \"config\": \"" + testConfig + "\",\n  \"profile\": \"\",\n  \"bridge\": \"192.168.1.2\",\n  \"username\": \"\",\n  \"timeout\": \"10s\",\n  \"output\": \"json\"\n}\n",
+		},
+		{
+			name: "Test unauthorized",
+			args: []string{"sensors", "list", "-bridge", server.Addr().String(), "-username", "nobody"},
+			code: exitUnauthorized,
+		},
+		{
+			name: "Test unreachable",
+			args: []string{"groups", "list", "-bridge", closed, "-username", username, "-timeout", "1s"},
+			code: exitUnreachable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := hugh(t, server, "", tt.args...)
+			if code != tt.code {
+				t.Fatalf("Expected exit code %d but got %d (%s)", tt.code, code, stderr)
+			}
+
+			if tt.stdout != "" && stdout != tt.stdout {
+				t.Errorf("Expected output:\n%s\nbut got:\n%s", tt.stdout, stdout)
+			}
+
+			if tt.check != nil {
+				tt.check(t, server.Bridge.Datastore())
+			}
+
+			if tt.reported != "" && strings.Count(stderr, tt.reported) != 1 {
+				t.Errorf("Expected `%s` to be reported once but got:\n%s", tt.reported, stderr)
+			}
+		})
+	}
+}
+
+func TestListJSON(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	username := server.Bridge.Register("hugh#test")
+
+	code, stdout, stderr := hugh(t, server, "", "-output", "json", "-bridge", server.Addr().String(), "-username", username, "groups", "list")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d but got %d (%s)", exitOK, code, stderr)
+	}
+
+	groups := map[string]api.Group{}
+	err := json.Unmarshal([]byte(stdout), &groups)
+	if err != nil {
+		t.Fatalf("Expected JSON but got `%s`", stdout)
+	}
+
+	if len(groups) != 2 || groups["1"].Name != "Kitchen" || groups["2"].Name != "Downstairs" {
+		t.Errorf("Unexpected groups %+v", groups)
+	}
+}
+
+func TestDiscoverAndPair(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	code, stdout, stderr := hugh(t, server, "", "discover", "-timeout", "100ms")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d but got %d (%s)", exitOK, code, stderr)
+	}
+
+	if !strings.Contains(stdout, server.Addr().String()) {
+		t.Errorf("Expected the bridge to be listed but got `%s`", stdout)
+	}
+
+	// Progress is logged to stderr so stdout only holds the bridges
+	code, stdout, stderr = hugh(t, server, "", "discover", "-timeout", "100ms", "-output", "json")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d but got %d (%s)", exitOK, code, stderr)
+	}
+
+	var bridges []api.Bridge
+	err := json.Unmarshal([]byte(stdout), &bridges)
+	if err != nil || len(bridges) != 1 || bridges[0].Port != server.Addr().Port {
+		t.Errorf("Expected the bridge as JSON but got `%s`", stdout)
+	}
+
+	if !strings.Contains(stderr, "Found Hue bridge") {
+		t.Errorf("Expected progress on stderr but got `%s`", stderr)
+	}
+
+	buttonPollInterval = 10 * time.Millisecond
+	defer func() { buttonPollInterval = time.Second }()
+
+	code, _, stderr = hugh(t, server, "", "pair", "-bridge", server.Addr().String(), "-button-timeout", "100ms")
+	if code != exitUnauthorized {
+		t.Errorf("Expected exit code %d without pressing the link button but got %d", exitUnauthorized, code)
+	}
+
+	if !strings.Contains(stderr, "Waiting up to 100ms") {
+		t.Errorf("Expected to wait for the link button without a terminal but got `%s`", stderr)
+	}
+
+	// The button is pressed while pair waits, and the bridge is found by its ID
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.Bridge.PressLinkButton()
+	}()
+
+	id := strings.ToUpper(server.Bridge.Datastore().Config.BridgeID)
+	code, stdout, stderr = hugh(t, server, "", "pair", "-bridge", id, "-timeout", "100ms", "-wait-for-button", "-output", "json")
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d but got %d (%s)", exitOK, code, stderr)
+	}
+
+	var result pairing
+	err = json.Unmarshal([]byte(stdout), &result)
+	if err != nil {
+		t.Fatalf("Expected JSON but got `%s`", stdout)
+	}
+
+	_, ok := server.Bridge.Datastore().Config.Whitelist[result.Username]
+	if !ok || result.Bridge != server.Addr().String() || result.ClientKey == "" || !strings.EqualFold(result.ID, id) {
+		t.Errorf("Expected a new user on the bridge but got %+v", result)
+	}
+}
+
+func TestChooseBridge(t *testing.T) {
+	first := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer first.Close()
+
+	store := fakebridge.DefaultDatastore()
+	store.Config.BridgeID = "001788FFFE000001"
+	second := fakebridge.NewServer(store)
+	defer second.Close()
+
+	username := second.Bridge.Register("hugh#test")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "Test bridge chosen by ID", args: []string{"-bridge", "001788fffe000001", "-username", username}, code: exitOK},
+		{name: "Test unknown ID", args: []string{"-bridge", "001788fffe000002", "-username", username}, code: exitNotFound},
+		{name: "Test invalid address", args: []string{"-bridge", "bridge.local", "-username", username}, code: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			app := &app{
+				stdin:   strings.NewReader(""),
+				stdout:  &stdout,
+				stderr:  &stderr,
+				browser: &fakebridge.Browser{Entries: []*zeroconf.ServiceEntry{first.ServiceEntry(), second.ServiceEntry()}},
+			}
+
+			code := app.run(append([]string{"lights", "list", "-timeout", "100ms"}, tt.args...))
+			if code != tt.code {
+				t.Errorf("Expected exit code %d but got %d (%s)", tt.code, code, stderr.String())
+			}
+		})
+	}
+
+	// Without a terminal there's nobody to ask which bridge to pair with
+	var stdout, stderr bytes.Buffer
+	app := &app{
+		stdin:   strings.NewReader("0\n"),
+		stdout:  &stdout,
+		stderr:  &stderr,
+		browser: &fakebridge.Browser{Entries: []*zeroconf.ServiceEntry{first.ServiceEntry(), second.ServiceEntry()}},
+	}
+
+	code := app.run([]string{"pair", "-timeout", "100ms"})
+	if code != exitUsage || !strings.Contains(stderr.String(), "Found 2 bridges") {
+		t.Errorf("Expected exit code %d but got %d (%s)", exitUsage, code, stderr.String())
+	}
+}
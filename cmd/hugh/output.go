@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML, outputCSV}
+
+func validOutput(format string) bool {
+	for _, known := range outputFormats {
+		if format == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listing holds what a command prints
+// Tables and CSV show the rows. JSON and YAML encode the value using its JSON tags, so the value should
+// have the same shape as the bridge's responses (resources keyed by their ID for example).
+type listing struct {
+	headers []string
+	rows    [][]string
+	value   interface{}
+}
+
+// print writes a listing to stdout in the requested output format
+func (app *app) print(output listing) error {
+	switch app.output {
+	case outputJSON:
+		encoder := json.NewEncoder(app.stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(output.value)
+	case outputYAML:
+		encoded, err := toYAML(output.value)
+		if err != nil {
+			return err
+		}
+
+		_, err = app.stdout.Write(encoded)
+		return err
+	case outputCSV:
+		writer := csv.NewWriter(app.stdout)
+		writer.Write(output.headers)
+		writer.WriteAll(output.rows)
+
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(app.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(output.headers, "\t"))
+		for _, row := range output.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+
+		return writer.Flush()
+	}
+}
+
+// toYAML encodes a value as YAML using its JSON tags
+// The value is encoded as JSON and re-read as a YAML document (JSON is valid YAML), which keeps the order of
+// the fields. The JSON formatting (flow style and quoted strings) is then dropped for YAML's block style.
+func toYAML(value interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(encoded, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	blockStyle(&document)
+
+	return yaml.Marshal(&document)
+}
+
+// yaml11Booleans holds the (lowercase) strings YAML 1.1 reads as booleans
+var yaml11Booleans = map[string]bool{"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true}
+
+// blockStyle resets the style of a node and its children so they're written in YAML's block style
+// Strings that YAML 1.1 parsers read as booleans (like the `on` attribute of light states) stay quoted.
+func blockStyle(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" || !yaml11Booleans[strings.ToLower(node.Value)] {
+		node.Style = 0
+	}
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	type light struct {
+		Name       string `json:"name"`
+		Brightness int    `json:"bri"`
+		On         bool   `json:"on"`
+	}
+
+	output := listing{
+		headers: []string{"ID", "NAME", "BRIGHTNESS"},
+		rows:    [][]string{{"1", "Kitchen, left", "254"}, {"10", "Hallway", "1"}},
+		value: map[string]light{
+			"1":  {Name: "Kitchen, left", Brightness: 254, On: true},
+			"10": {Name: "Hallway", Brightness: 1},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:     "Test table",
+			format:   outputTable,
+			expected: "ID  NAME           BRIGHTNESS\n1   Kitchen, left  254\n10  Hallway        1\n",
+		},
+		{
+			name:     "Test CSV",
+			format:   outputCSV,
+			expected: "ID,NAME,BRIGHTNESS\n1,\"Kitchen, left\",254\n10,Hallway,1\n",
+		},
+		{
+			name:     "Test JSON",
+			format:   outputJSON,
+			expected: "{\n  \"1\": {\n    \"name\": \"Kitchen, left\",\n    \"bri\": 254,\n    \"on\": true\n  },\n  \"10\": {\n    \"name\": \"Hallway\",\n    \"bri\": 1,\n    \"on\": false\n  }\n}\n",
+		},
+		{
+			name:     "Test YAML",
+			format:   outputYAML,
+			expected: "\"1\":\n    name: Kitchen, left\n    bri: 254\n    \"on\": true\n\"10\":\n    name: Hallway\n    bri: 1\n    \"on\": false\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			app := &app{stdout: &stdout, output: tt.format}
+
+			err := app.print(output)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if stdout.String() != tt.expected {
+				t.Errorf("Expected output:\n%s\nbut got:\n%s", tt.expected, stdout.String())
+			}
+		})
+	}
+}
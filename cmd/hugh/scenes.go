@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+func (app *app) listScenes(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	scenes, err := bridge.GetScenes()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(scenes, func(i, j int) bool {
+		return scenes[i].ID < scenes[j].ID
+	})
+
+	output := listing{
+		headers: []string{"ID", "NAME", "TYPE", "GROUP", "LIGHTS"},
+		value:   map[string]api.Scene{},
+	}
+	for _, scene := range scenes {
+		output.value.(map[string]api.Scene)[scene.ID] = scene
+		output.rows = append(output.rows, []string{
+			scene.ID,
+			scene.Name,
+			scene.Type,
+			scene.Group,
+			strings.Join(scene.Lights, ","),
+		})
+	}
+
+	return app.print(output)
+}
+
+func (app *app) recallScene(name string, args []string) error {
+	flags := app.flagSet(name)
+	positional, err := app.parse(flags, args, "<scene> <group>")
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 2 {
+		return usageErrorf("A scene and a group must be given")
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	scenes, err := bridge.GetScenes()
+	if err != nil {
+		return err
+	}
+
+	sceneID := ""
+	for _, scene := range scenes {
+		if scene.ID == positional[0] || strings.EqualFold(scene.Name, positional[0]) {
+			sceneID = scene.ID
+			break
+		}
+	}
+
+	if sceneID == "" {
+		return fmt.Errorf("Scene `%s`: %w", positional[0], errNotFound)
+	}
+
+	groups, err := sortedGroups(bridge)
+	if err != nil {
+		return err
+	}
+
+	group, err := resolveGroup(groups, positional[1])
+	if err != nil {
+		return err
+	}
+
+	return group.RecallSceneContext(context.Background(), sceneID)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+func (app *app) listSensors(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	sensors, err := bridge.GetSensors()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(sensors, func(i, j int) bool {
+		return lessID(sensors[i].ID, sensors[j].ID)
+	})
+
+	output := listing{
+		headers: []string{"ID", "NAME", "TYPE", "MODEL"},
+		value:   map[string]api.Sensor{},
+	}
+	for _, sensor := range sensors {
+		output.value.(map[string]api.Sensor)[sensor.ID] = sensor
+		output.rows = append(output.rows, []string{sensor.ID, sensor.Name, sensor.Type, sensor.ModelID})
+	}
+
+	return app.print(output)
+}
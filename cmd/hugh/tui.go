@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func (app *app) tui(name string, args []string) error {
+	flags := app.flagSet(name)
+	_, err := app.parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+
+	bridge, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	lights, err := sortedLights(bridge)
+	if err != nil {
+		return err
+	}
+
+	groups, err := sortedGroups(bridge)
+	if err != nil {
+		return err
+	}
+
+	scenes, err := bridge.GetScenes()
+	if err != nil {
+		return err
+	}
+
+	ui := tview.NewApplication()
+	newTUI(ui, bridge, lights, groups, scenes)
+
+	return ui.Run()
+}
+
+// tui is the interactive interface started by the tui command
+// It has pages for lights, rooms, zones and scenes. Changes to lights are sent through a coalescer so
+// dragging a slider sends as few requests as the bridge allows, and the UI is updated with the state the
+// bridge reports.
+type tui struct {
+	ui     *tview.Application
+	bridge *api.Bridge
+
+	// Runs a function on the UI's goroutine (tests run them directly)
+	queue func(func())
+
+	coalescer *api.Coalescer
+
+	// Changes made in the UI shouldn't have to wait behind bulk changes
+	interactive context.Context
+
+	// The lights shown (only touched on the UI's goroutine)
+	lights []api.Light
+
+	groups []api.Group
+	scenes []api.Scene
+
+	header *tview.TextView
+	pages  *tview.Pages
+	footer *tview.Pages
+	status *tview.TextView
+
+	lightList    *tview.List
+	lightDetails *tview.List
+	shown        int // The index of the light in the details list (-1 if there isn't one)
+
+	rooms, zones *groupView
+	sending      map[string]*api.LightStateUpdate // Changes to groups waiting for an earlier change to be sent
+
+	sceneList   *tview.List
+	sceneGroups *tview.List
+}
+
+// Names of the pages in the order they're shown in the header
+const (
+	pageLights = "lights"
+	pageRooms  = "rooms"
+	pageZones  = "zones"
+	pageScenes = "scenes"
+)
+
+var pageNames = []string{pageLights, pageRooms, pageZones, pageScenes}
+
+// Names of the footer's pages
+const (
+	footerStatus = "status"
+	footerSlider = "slider"
+)
+
+// newTUI builds the interface and makes it the application's root
+// Scenes are shown sorted by name.
+func newTUI(ui *tview.Application, bridge *api.Bridge, lights []api.Light, groups []api.Group, scenes []api.Scene) *tui {
+	t := &tui{
+		ui:          ui,
+		bridge:      bridge,
+		queue:       func(update func()) { ui.QueueUpdateDraw(update) },
+		coalescer:   api.NewCoalescer(),
+		interactive: api.WithPriority(context.Background(), api.PriorityHigh),
+		lights:      lights,
+		groups:      groups,
+		scenes:      scenes,
+		shown:       -1,
+		sending:     map[string]*api.LightStateUpdate{},
+	}
+
+	sort.SliceStable(t.scenes, func(i, j int) bool {
+		return t.scenes[i].Name < t.scenes[j].Name
+	})
+
+	t.status = tview.NewTextView().SetDynamicColors(true)
+	t.footer = tview.NewPages().AddPage(footerStatus, t.status, true, true)
+
+	t.rooms = t.newGroupView(api.GroupTypeRoom, "Rooms")
+	t.zones = t.newGroupView(api.GroupTypeZone, "Zones")
+
+	t.pages = tview.NewPages().
+		AddPage(pageLights, t.lightsPage(), true, true).
+		AddPage(pageRooms, t.rooms.page(), true, false).
+		AddPage(pageZones, t.zones.page(), true, false).
+		AddPage(pageScenes, t.scenesPage(), true, false)
+
+	t.header = tview.NewTextView().SetRegions(true).SetDynamicColors(true)
+	for i, name := range pageNames {
+		fmt.Fprintf(t.header, `["%s"] %d %s [""] `, name, i+1, strings.ToUpper(name[:1])+name[1:])
+	}
+	t.header.Highlight(pageLights)
+
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.header, 1, 0, false).
+		AddItem(t.pages, 0, 1, true).
+		AddItem(t.footer, 1, 0, false)
+	ui.SetRoot(root, true)
+	ui.SetInputCapture(t.switchPages)
+
+	return t
+}
+
+// switchPages switches pages with the number keys, tab and backtab
+// Keys are left alone while a slider has focus so numbers can be typed in.
+func (t *tui) switchPages(event *tcell.EventKey) *tcell.EventKey {
+	if _, typing := t.ui.GetFocus().(*tview.InputField); typing {
+		return event
+	}
+
+	current, _ := t.pages.GetFrontPage()
+	index := 0
+	for i, name := range pageNames {
+		if name == current {
+			index = i
+		}
+	}
+
+	switch {
+	case event.Key() == tcell.KeyTab:
+		index = (index + 1) % len(pageNames)
+	case event.Key() == tcell.KeyBacktab:
+		index = (index + len(pageNames) - 1) % len(pageNames)
+	case event.Key() == tcell.KeyRune && event.Rune() >= '1' && int(event.Rune()-'1') < len(pageNames):
+		index = int(event.Rune() - '1')
+	default:
+		return event
+	}
+
+	t.showPage(pageNames[index])
+
+	return nil
+}
+
+// showPage switches to a page and focuses its main list
+func (t *tui) showPage(name string) {
+	t.pages.SwitchToPage(name)
+	t.header.Highlight(name)
+
+	switch name {
+	case pageLights:
+		t.ui.SetFocus(t.lightList)
+	case pageRooms:
+		t.ui.SetFocus(t.rooms.list)
+	case pageZones:
+		t.ui.SetFocus(t.zones.list)
+	case pageScenes:
+		t.ui.SetFocus(t.sceneList)
+	}
+}
+
+// refresh reads every light's state from the bridge
+// It's used after changes to groups, which change lights without saying how.
+func (t *tui) refresh() {
+	go func() {
+		lights, err := t.bridge.GetLights()
+		t.queue(func() {
+			if err != nil {
+				t.report(err)
+				return
+			}
+
+			for _, light := range lights {
+				if index := t.lightIndex(light.ID); index >= 0 {
+					t.lights[index].State = light.State
+				}
+			}
+
+			t.redraw()
+		})
+	}()
+}
+
+// redraw shows the current state of the lights on every page
+func (t *tui) redraw() {
+	if t.shown >= 0 {
+		t.showLight(t.shown)
+	}
+
+	t.rooms.redraw()
+	t.zones.redraw()
+}
+
+// lightIndex returns the index of the light with an ID (-1 if there isn't one)
+func (t *tui) lightIndex(id string) int {
+	for i, light := range t.lights {
+		if light.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// groupIndex returns the index of the group with an ID (-1 if there isn't one)
+func (t *tui) groupIndex(id string) int {
+	for i, group := range t.groups {
+		if group.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// report shows an error (or clears the status line if there isn't one)
+func (t *tui) report(err error) {
+	if err == nil {
+		t.status.SetText("")
+		return
+	}
+
+	t.status.SetText("[red]" + tview.Escape(err.Error()))
+}
+
+// inform shows a message in the status line
+func (t *tui) inform(format string, args ...interface{}) {
+	t.status.SetText(tview.Escape(fmt.Sprintf(format, args...)))
+}
+
+// lightsPage lists the lights next to the details of the selected light
+func (t *tui) lightsPage() tview.Primitive {
+	t.lightList = tview.NewList()
+	t.lightList.SetBorder(true).SetTitle("Lights")
+
+	t.lightDetails = tview.NewList()
+	t.lightDetails.SetBorder(true).SetTitle("Light Info")
+	t.lightDetails.SetDoneFunc(func() {
+		t.shown = -1
+		t.lightDetails.Clear()
+		t.ui.SetFocus(t.lightList)
+	})
+
+	for i, light := range t.lights {
+		i := i
+		t.lightList.AddItem(light.Name, "", 0, func() {
+			t.showLight(i)
+			t.ui.SetFocus(t.lightDetails)
+		})
+	}
+
+	return tview.NewFlex().
+		AddItem(t.lightList, 0, 1, true).
+		AddItem(t.lightDetails, 0, 1, false)
+}
+
+// showLight fills the details list with a light's state and the controls it supports
+func (t *tui) showLight(index int) {
+	current := t.lightDetails.GetCurrentItem()
+	if index != t.shown {
+		current = 0
+	}
+
+	t.shown = index
+	t.lightDetails.Clear()
+
+	light := &t.lights[index]
+	t.lightDetails.AddItem("Active", onOff(light.State.On), 'a', func() {
+		t.submit(index, api.NewStateBuilder().On(!light.State.On))
+	})
+
+	for _, control := range lightControls(light) {
+		control := control
+		if !light.Supports(control.feature) {
+			t.lightDetails.AddItem("[gray]"+control.name, "[gray]Not supported", 0, nil)
+			continue
+		}
+
+		t.lightDetails.AddItem(control.name, control.describe(light.State), control.shortcut, func() {
+			t.adjust(index, control)
+		})
+	}
+
+	if light.Supports(api.FeatureColor) {
+		coords := light.State.CIECoords
+		t.lightDetails.AddItem("Color", fmt.Sprintf("x: %.4f y: %.4f", coords[0], coords[1]), 0, nil)
+	}
+
+	if light.State.ColorMode != "" {
+		t.lightDetails.AddItem("Color mode", light.State.ColorMode, 0, nil)
+	}
+
+	t.lightDetails.AddItem("Reachable", strconv.FormatBool(light.State.Reachable), 0, nil)
+
+	for i, group := range t.groups {
+		if group.Type != api.GroupTypeRoom || !contains(group.Lights, light.ID) {
+			continue
+		}
+
+		i := i
+		t.lightDetails.AddItem("Room", group.Name, 'r', func() {
+			t.showPage(pageRooms)
+			t.rooms.show(i)
+		})
+	}
+
+	t.lightDetails.SetCurrentItem(current)
+}
+
+// selectLight shows a light on the lights page
+func (t *tui) selectLight(index int) {
+	t.showPage(pageLights)
+	t.lightList.SetCurrentItem(index)
+	t.showLight(index)
+	t.ui.SetFocus(t.lightDetails)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// adjust shows a slider for one of a light's controls in the footer
+func (t *tui) adjust(index int, control lightControl) {
+	light := &t.lights[index]
+	slider := newSlider(control, control.value(light.State), func(value int) {
+		t.submit(index, control.set(api.NewStateBuilder(), value))
+	})
+
+	slider.SetDoneFunc(func(key tcell.Key) {
+		t.footer.RemovePage(footerSlider)
+		t.ui.SetFocus(t.lightDetails)
+	})
+
+	t.footer.AddAndSwitchToPage(footerSlider, slider, true)
+	t.ui.SetFocus(slider)
+}
+
+// submit sends a change to a light
+// The light is shown with the state the bridge reports once the (possibly merged) request is answered.
+func (t *tui) submit(index int, builder *api.StateBuilder) {
+	light := &t.lights[index]
+	update, err := builder.Build(light)
+	if err != nil {
+		t.report(err)
+		return
+	}
+
+	pending := t.coalescer.Submit(t.interactive, light, update)
+	go func() {
+		state, err := pending.Wait()
+		t.queue(func() {
+			t.report(err)
+			if err != nil {
+				return
+			}
+
+			light.State = state
+			t.redraw()
+		})
+	}()
+}
+
+// lightControl is a light attribute that can be adjusted with a slider
+type lightControl struct {
+	name     string
+	shortcut rune
+	feature  api.Feature // What the light has to support for the control to be enabled
+
+	minimum, maximum, step int
+
+	value func(state api.LightState) int
+	set   func(builder *api.StateBuilder, value int) *api.StateBuilder
+}
+
+// describe formats the control's current value
+func (control lightControl) describe(state api.LightState) string {
+	value := control.value(state)
+	if control.feature == api.FeatureColorTemperature {
+		return fmt.Sprintf("%d mireds (%dK)", value, api.MiredToKelvin(uint(value)))
+	}
+
+	return strconv.Itoa(value)
+}
+
+// lightControls returns the controls shown for a light
+// The temperature control is limited to the light's color temperature range.
+func lightControls(light *api.Light) []lightControl {
+	tempRange := light.TemperatureRange()
+
+	return []lightControl{
+		{
+			name: "Brightness", shortcut: 'b', feature: api.FeatureBrightness,
+			minimum: api.MinimumBrightness, maximum: api.MaximumBrightness, step: 10,
+			value: func(state api.LightState) int { return int(state.Brightness) },
+			set:   (*api.StateBuilder).Brightness,
+		},
+		{
+			name: "Hue", shortcut: 'h', feature: api.FeatureColor,
+			minimum: 0, maximum: 65535, step: 1000,
+			value: func(state api.LightState) int { return int(state.Hue) },
+			set:   (*api.StateBuilder).Hue,
+		},
+		{
+			name: "Saturation", shortcut: 's', feature: api.FeatureColor,
+			minimum: 0, maximum: 254, step: 10,
+			value: func(state api.LightState) int { return int(state.Saturation) },
+			set:   (*api.StateBuilder).Saturation,
+		},
+		{
+			name: "Color temperature", shortcut: 't', feature: api.FeatureColorTemperature,
+			minimum: int(tempRange.Minimum), maximum: int(tempRange.Maximum), step: 10,
+			value: func(state api.LightState) int { return int(state.Temperature) },
+			set:   (*api.StateBuilder).Temperature,
+		},
+	}
+}
+
+// newSlider creates an input for a control's value
+// The arrow keys (and + and -) move the value by the control's step, page up and page down move it to the
+// ends of the range, and numbers can be typed in. Values are passed to changed as soon as they're moved to
+// and typed values once enter is pressed (so partially typed numbers aren't sent).
+func newSlider(control lightControl, value int, changed func(int)) *tview.InputField {
+	slider := tview.NewInputField().
+		SetLabel(fmt.Sprintf("%s (%d to %d, ←/→ to adjust): ", control.name, control.minimum, control.maximum)).
+		SetFieldWidth(8).
+		SetAcceptanceFunc(tview.InputFieldInteger).
+		SetText(strconv.Itoa(value))
+
+	sent := value
+	send := func(value int) {
+		sent = value
+		changed(value)
+	}
+
+	move := func(value int) {
+		if value < control.minimum {
+			value = control.minimum
+		}
+
+		if value > control.maximum {
+			value = control.maximum
+		}
+
+		slider.SetText(strconv.Itoa(value))
+		send(value)
+	}
+
+	slider.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		current, err := strconv.Atoi(slider.GetText())
+		if err != nil {
+			current = value
+		}
+
+		switch {
+		case event.Key() == tcell.KeyRight, event.Key() == tcell.KeyUp, event.Rune() == '+':
+			move(current + control.step)
+		case event.Key() == tcell.KeyLeft, event.Key() == tcell.KeyDown, event.Rune() == '-':
+			move(current - control.step)
+		case event.Key() == tcell.KeyPgUp:
+			move(control.maximum)
+		case event.Key() == tcell.KeyPgDn:
+			move(control.minimum)
+		case event.Key() == tcell.KeyEnter:
+			// The slider is closed by its done func after the typed value is sent
+			if err == nil && current != sent && current >= control.minimum && current <= control.maximum {
+				send(current)
+			}
+			return event
+		default:
+			return event
+		}
+
+		return nil
+	})
+
+	return slider
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// groupView is a TUI page listing groups of one type (rooms or zones) next to the selected group's details
+// A group's state is worked out from its lights so it stays in sync with changes made on the lights page.
+type groupView struct {
+	t       *tui
+	kind    string // The type of group shown (api.GroupTypeRoom for example)
+	list    *tview.List
+	details *tview.List
+	indexes []int // The indexes (in the TUI's groups) of the groups in the list
+	shown   int   // The index (in the TUI's groups) of the group in the details list (-1 if there isn't one)
+}
+
+func (t *tui) newGroupView(kind, title string) *groupView {
+	view := &groupView{
+		t:       t,
+		kind:    kind,
+		list:    tview.NewList(),
+		details: tview.NewList(),
+		shown:   -1,
+	}
+
+	view.list.SetBorder(true).SetTitle(title)
+	view.details.SetBorder(true).SetTitle("Group Info")
+	view.details.SetDoneFunc(func() {
+		view.shown = -1
+		view.details.Clear()
+		t.ui.SetFocus(view.list)
+	})
+
+	for i, group := range t.groups {
+		if group.Type != kind {
+			continue
+		}
+
+		i := i
+		view.indexes = append(view.indexes, i)
+		view.list.AddItem(group.Name, "", 0, func() {
+			view.show(i)
+		})
+	}
+
+	view.redraw()
+
+	return view
+}
+
+func (view *groupView) page() tview.Primitive {
+	return tview.NewFlex().
+		AddItem(view.list, 0, 1, true).
+		AddItem(view.details, 0, 1, false)
+}
+
+// show selects a group and fills the details list with its state, controls and lights
+func (view *groupView) show(index int) {
+	for item, candidate := range view.indexes {
+		if candidate == index {
+			view.list.SetCurrentItem(item)
+		}
+	}
+
+	view.fill(index)
+	view.t.ui.SetFocus(view.details)
+}
+
+// redraw shows the current state of the groups
+func (view *groupView) redraw() {
+	for item, index := range view.indexes {
+		view.list.SetItemText(item, view.t.groups[index].Name, view.t.groupStatus(index))
+	}
+
+	if view.shown >= 0 {
+		view.fill(view.shown)
+	}
+}
+
+func (view *groupView) fill(index int) {
+	t := view.t
+	current := view.details.GetCurrentItem()
+	if index != view.shown {
+		current = 0
+	}
+
+	view.shown = index
+	view.details.Clear()
+
+	group := t.groups[index]
+	on, _, brightness, dimmable := t.groupLights(index)
+	view.details.AddItem("Active", t.groupStatus(index), 'a', func() {
+		t.sendGroup(index, api.NewStateBuilder().On(on == 0))
+	})
+
+	control := lightControls(&api.Light{})[0]
+	if dimmable {
+		view.details.AddItem(control.name, control.describe(api.LightState{Brightness: brightness}), control.shortcut, func() {
+			view.adjust(index, control, int(brightness))
+		})
+	} else {
+		view.details.AddItem("[gray]"+control.name, "[gray]Not supported", 0, nil)
+	}
+
+	for _, id := range group.Lights {
+		light := t.lightIndex(id)
+		if light < 0 {
+			continue
+		}
+
+		state := t.lights[light].State
+		description := onOff(state.On)
+		if state.On && t.lights[light].Supports(api.FeatureBrightness) {
+			description = fmt.Sprintf("on (brightness %d)", state.Brightness)
+		}
+
+		view.details.AddItem("Light: "+t.lights[light].Name, description, 0, func() {
+			t.selectLight(light)
+		})
+	}
+
+	view.details.SetCurrentItem(current)
+}
+
+// adjust shows a slider for the brightness of a group's lights in the footer
+func (view *groupView) adjust(index int, control lightControl, value int) {
+	t := view.t
+	slider := newSlider(control, value, func(value int) {
+		t.sendGroup(index, control.set(api.NewStateBuilder(), value))
+	})
+
+	slider.SetDoneFunc(func(key tcell.Key) {
+		t.footer.RemovePage(footerSlider)
+		t.ui.SetFocus(view.details)
+	})
+
+	t.footer.AddAndSwitchToPage(footerSlider, slider, true)
+	t.ui.SetFocus(slider)
+}
+
+// groupLights summarizes a group's lights: how many are on, how many there are, the brightest light that's
+// on (or the brightest light if none are on) and whether any of them can be dimmed
+func (t *tui) groupLights(index int) (on, total int, brightness uint8, dimmable bool) {
+	var brightest, brightestOn uint8
+	for _, id := range t.groups[index].Lights {
+		light := t.lightIndex(id)
+		if light < 0 {
+			continue
+		}
+
+		total++
+		state := t.lights[light].State
+		if state.On {
+			on++
+		}
+
+		if !t.lights[light].Supports(api.FeatureBrightness) {
+			continue
+		}
+
+		dimmable = true
+		if state.Brightness > brightest {
+			brightest = state.Brightness
+		}
+
+		if state.On && state.Brightness > brightestOn {
+			brightestOn = state.Brightness
+		}
+	}
+
+	if on > 0 && brightestOn > 0 {
+		return on, total, brightestOn, dimmable
+	}
+
+	return on, total, brightest, dimmable
+}
+
+// groupStatus describes how many of a group's lights are on
+func (t *tui) groupStatus(index int) string {
+	on, total, _, _ := t.groupLights(index)
+	switch {
+	case total == 0:
+		return "no lights"
+	case on == 0:
+		return "off"
+	case on == total:
+		return "on"
+	}
+
+	return fmt.Sprintf("%d of %d lights on", on, total)
+}
+
+// sendGroup sends a change to a group's action
+// The bridge handles about one group command per second, so changes made while an earlier change to the
+// same group is being sent are merged (the latest value of each attribute wins) and sent together once it
+// has been answered. The lights are read back afterwards since the bridge doesn't say what changed.
+func (t *tui) sendGroup(index int, builder *api.StateBuilder) {
+	update, err := builder.Build(nil)
+	if err != nil {
+		t.report(err)
+		return
+	}
+
+	group := t.groups[index]
+	if waiting, ok := t.sending[group.ID]; ok {
+		if waiting == nil {
+			waiting = &api.LightStateUpdate{}
+			t.sending[group.ID] = waiting
+		}
+
+		if update.On != nil {
+			waiting.On = update.On
+		}
+
+		if update.Brightness != nil {
+			waiting.Brightness = update.Brightness
+		}
+
+		return
+	}
+
+	t.sending[group.ID] = nil
+
+	var send func(update api.LightStateUpdate)
+	send = func(update api.LightStateUpdate) {
+		go func() {
+			err := group.SetStateContext(t.interactive, update)
+			t.queue(func() {
+				t.report(err)
+
+				waiting := t.sending[group.ID]
+				if waiting != nil {
+					t.sending[group.ID] = nil
+					send(*waiting)
+					return
+				}
+
+				delete(t.sending, group.ID)
+				t.refresh()
+			})
+		}()
+	}
+
+	send(update)
+}
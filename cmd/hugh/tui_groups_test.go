@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/gdamore/tcell/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGroupView(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	ui, updates := testTUI(t, server)
+
+	if diff := cmp.Diff([]string{"Kitchen: off"}, items(ui.rooms.list)); diff != "" {
+		t.Errorf("Unexpected rooms (-want +got):\\n%s", diff)
+	}
+
+	ui.showPage(pageRooms)
+	press(ui.rooms.list, tcell.KeyEnter, 0)
+
+	expected := []string{"Active: off", "Brightness: 254", "Light: Kitchen 1: off", "Light: Kitchen 2: off"}
+	if diff := cmp.Diff(expected, items(ui.rooms.details)); diff != "" {
+		t.Errorf("Unexpected details (-want +got):\\n%s", diff)
+	}
+
+	// Turning the room on is shown once the lights are read back
+	press(ui.rooms.details, tcell.KeyRune, 'a')
+	apply(t, updates)
+	apply(t, updates)
+
+	expected = []string{"Active: on", "Brightness: 254", "Light: Kitchen 1: on (brightness 254)", "Light: Kitchen 2: on (brightness 254)"}
+	if diff := cmp.Diff(expected, items(ui.rooms.details)); diff != "" {
+		t.Errorf("Unexpected details (-want +got):\\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"Downstairs: 2 of 3 lights on"}, items(ui.zones.list)); diff != "" {
+		t.Errorf("Unexpected zones (-want +got):\\n%s", diff)
+	}
+
+	// Changes made while the first is sent are merged
+	press(ui.rooms.details, tcell.KeyRune, 'b')
+	_, slider := ui.footer.GetFrontPage()
+	press(slider, tcell.KeyPgDn, 0)
+	press(slider, tcell.KeyRight, 0)
+	press(slider, tcell.KeyRight, 0)
+	apply(t, updates)
+	apply(t, updates)
+	apply(t, updates)
+
+	select {
+	case <-updates:
+		t.Error("Expected the changes to be merged")
+	default:
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if brightness := server.Bridge.Datastore().Lights[id].State.Brightness; brightness != 21 {
+			t.Errorf("Expected light %s to have brightness 21 but got %d", id, brightness)
+		}
+	}
+
+	if _, text := ui.rooms.details.GetItemText(1); text != "21" {
+		t.Errorf("Expected the new brightness to be shown but got %s", text)
+	}
+
+	press(slider, tcell.KeyEnter, 0)
+
+	// The room's lights can be opened on the lights page
+	ui.rooms.details.SetCurrentItem(3)
+	press(ui.rooms.details, tcell.KeyEnter, 0)
+
+	if page, _ := ui.pages.GetFrontPage(); page != pageLights || ui.shown != 1 || ui.ui.GetFocus() != ui.lightDetails {
+		t.Errorf("Expected Kitchen 2 to be shown but got %d on the %s page", ui.shown, page)
+	}
+
+	// And the light's room can be opened from there
+	press(ui.lightDetails, tcell.KeyRune, 'r')
+
+	if page, _ := ui.pages.GetFrontPage(); page != pageRooms || ui.rooms.shown != 0 || ui.ui.GetFocus() != ui.rooms.details {
+		t.Errorf("Expected the kitchen to be shown but got %d on the %s page", ui.rooms.shown, page)
+	}
+}
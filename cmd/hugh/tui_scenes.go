@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/rivo/tview"
+)
+
+// scenesPage lists the scenes next to the rooms and zones a selected scene can be recalled in
+func (t *tui) scenesPage() tview.Primitive {
+	t.sceneList = tview.NewList()
+	t.sceneList.SetBorder(true).SetTitle("Scenes")
+
+	t.sceneGroups = tview.NewList()
+	t.sceneGroups.SetBorder(true).SetTitle("Recall In")
+	t.sceneGroups.SetDoneFunc(func() {
+		t.sceneGroups.Clear()
+		t.ui.SetFocus(t.sceneList)
+	})
+
+	for i, scene := range t.scenes {
+		i := i
+		description := "All lights"
+		if group := t.groupIndex(scene.Group); scene.Type == api.SceneTypeGroup && group >= 0 {
+			description = t.groups[group].Name
+		}
+
+		t.sceneList.AddItem(scene.Name, description, 0, func() {
+			t.showScene(i)
+		})
+	}
+
+	return tview.NewFlex().
+		AddItem(t.sceneList, 0, 1, true).
+		AddItem(t.sceneGroups, 0, 1, false)
+}
+
+// showScene lists the rooms and zones sharing lights with a scene
+// The scene's own group (for group scenes) is listed first.
+func (t *tui) showScene(index int) {
+	scene := t.scenes[index]
+	t.sceneGroups.Clear()
+
+	candidates := []int{}
+	if own := t.groupIndex(scene.Group); scene.Type == api.SceneTypeGroup && own >= 0 {
+		candidates = append(candidates, own)
+	}
+
+	for i, group := range t.groups {
+		if group.ID == scene.Group || (group.Type != api.GroupTypeRoom && group.Type != api.GroupTypeZone) {
+			continue
+		}
+
+		for _, id := range group.Lights {
+			if contains(scene.Lights, id) {
+				candidates = append(candidates, i)
+				break
+			}
+		}
+	}
+
+	for _, i := range candidates {
+		i := i
+		t.sceneGroups.AddItem(t.groups[i].Name, t.groups[i].Type, 0, func() {
+			t.recall(index, i)
+		})
+	}
+
+	t.ui.SetFocus(t.sceneGroups)
+}
+
+// recall recalls a scene in a group
+// Only the lights in both the scene and the group are changed.
+func (t *tui) recall(scene, group int) {
+	name, target := t.scenes[scene].Name, t.groups[group]
+	id := t.scenes[scene].ID
+
+	go func() {
+		err := target.RecallSceneContext(t.interactive, id)
+		t.queue(func() {
+			if err != nil {
+				t.report(err)
+				return
+			}
+
+			t.inform("Recalled %s in %s", name, target.Name)
+			t.refresh()
+		})
+	}()
+}
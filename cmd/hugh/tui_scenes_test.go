@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/gdamore/tcell/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScenes(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	ui, updates := testTUI(t, server)
+	ui.showPage(pageScenes)
+
+	if diff := cmp.Diff([]string{"Relax: Kitchen"}, items(ui.sceneList)); diff != "" {
+		t.Errorf("Unexpected scenes (-want +got):\n%s", diff)
+	}
+
+	// The scene's own room is listed first
+	press(ui.sceneList, tcell.KeyEnter, 0)
+	if diff := cmp.Diff([]string{"Kitchen: Room", "Downstairs: Zone"}, items(ui.sceneGroups)); diff != "" {
+		t.Errorf("Unexpected groups (-want +got):\n%s", diff)
+	}
+
+	ui.sceneGroups.SetCurrentItem(1)
+	press(ui.sceneGroups, tcell.KeyEnter, 0)
+	apply(t, updates)
+
+	if status := ui.status.GetText(true); status != "Recalled Relax in Downstairs" {
+		t.Errorf("Expected the recall to be reported but got `%s`", status)
+	}
+
+	apply(t, updates)
+
+	if diff := cmp.Diff([]string{"Downstairs: 2 of 3 lights on"}, items(ui.zones.list)); diff != "" {
+		t.Errorf("Expected the lights to be read back but got (-want +got):\n%s", diff)
+	}
+
+	if brightness := ui.lights[1].State.Brightness; brightness != 1 {
+		t.Errorf("Expected the scene's brightness to be shown but got %d", brightness)
+	}
+
+	// Later changes start from the state that was read back
+	ui.submit(1, api.NewStateBuilder().Temperature(300))
+	apply(t, updates)
+
+	if state := ui.lights[1].State; !state.On || state.Brightness != 1 || state.Temperature != 300 {
+		t.Errorf("Expected the light to keep the scene's state but got %+v", state)
+	}
+
+	// Leaving the groups returns to the scenes
+	press(ui.sceneGroups, tcell.KeyEscape, 0)
+	if ui.ui.GetFocus() != ui.sceneList || ui.sceneGroups.GetItemCount() != 0 {
+		t.Error("Expected to return to the scenes")
+	}
+}
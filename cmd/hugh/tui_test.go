@@ -0,0 +1,281 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/gdamore/tcell/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rivo/tview"
+)
+
+// press sends a key to a primitive as if it had focus
+func press(primitive tview.Primitive, key tcell.Key, r rune) {
+	primitive.InputHandler()(tcell.NewEventKey(key, r, tcell.ModNone), func(tview.Primitive) {})
+}
+
+func TestSlider(t *testing.T) {
+	control := lightControl{name: "Brightness", minimum: 1, maximum: 254, step: 10}
+
+	tests := []struct {
+		name     string
+		value    int
+		keys     []tcell.Key
+		runes    []rune
+		enter    bool
+		expected []int
+	}{
+		{name: "Test arrows", value: 100, keys: []tcell.Key{tcell.KeyRight, tcell.KeyUp, tcell.KeyLeft, tcell.KeyDown}, expected: []int{110, 120, 110, 100}},
+		{name: "Test plus and minus", value: 100, runes: []rune{'+', '-', '-'}, expected: []int{110, 100, 90}},
+		{name: "Test ends of the range", value: 100, keys: []tcell.Key{tcell.KeyPgUp, tcell.KeyPgDn}, expected: []int{254, 1}},
+		{name: "Test clamped", value: 250, keys: []tcell.Key{tcell.KeyRight, tcell.KeyRight}, expected: []int{254, 254}},
+		{name: "Test typing", value: 2, runes: []rune{'5', '0', 'x'}, enter: true, expected: []int{250}},
+		{name: "Test typing without enter", value: 2, runes: []rune{'5', '0'}, expected: []int{}},
+		{name: "Test enter after moving", value: 100, keys: []tcell.Key{tcell.KeyRight}, enter: true, expected: []int{110}},
+		{name: "Test values out of range are ignored", value: 30, runes: []rune{'0'}, enter: true, expected: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int{}
+			slider := newSlider(control, tt.value, func(value int) {
+				got = append(got, value)
+			})
+
+			for _, key := range tt.keys {
+				press(slider, key, 0)
+			}
+
+			for _, r := range tt.runes {
+				press(slider, tcell.KeyRune, r)
+			}
+
+			if tt.enter {
+				press(slider, tcell.KeyEnter, 0)
+			}
+
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Unexpected values (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testTUI creates a TUI for a fake bridge
+// Updates the TUI queues are sent on the returned channel instead of waiting for the application to run.
+func testTUI(t *testing.T, server *fakebridge.Server) (*tui, chan func()) {
+	t.Helper()
+
+	bridge := server.Endpoint(&api.API{})
+	bridge.Username = server.Bridge.Register("hugh#test")
+
+	lights, err := sortedLights(bridge)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	groups, err := sortedGroups(bridge)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	scenes, err := bridge.GetScenes()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	updates := make(chan func(), 10)
+	ui := newTUI(tview.NewApplication(), bridge, lights, groups, scenes)
+	ui.queue = func(update func()) {
+		updates <- update
+	}
+
+	return ui, updates
+}
+
+// apply runs the next update queued by the TUI
+func apply(t *testing.T, updates chan func()) {
+	t.Helper()
+
+	select {
+	case update := <-updates:
+		update()
+	case <-time.After(time.Second):
+		t.Fatal("Expected the TUI to be updated")
+	}
+}
+
+// items returns the text of a list's items
+func items(list *tview.List) []string {
+	texts := []string{}
+	for i := 0; i < list.GetItemCount(); i++ {
+		main, secondary := list.GetItemText(i)
+		texts = append(texts, main+": "+secondary)
+	}
+
+	return texts
+}
+
+func TestLightDetails(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	ui, _ := testTUI(t, server)
+
+	tests := []struct {
+		name     string
+		index    int
+		expected []string
+	}{
+		{
+			name:  "Test color light",
+			index: 0,
+			expected: []string{
+				"Active: off",
+				"Brightness: 254",
+				"Hue: 8418",
+				"Saturation: 140",
+				"Color temperature: 366 mireds (2732K)",
+				"Color: x: 0.4573 y: 0.4100",
+				"Color mode: ct",
+				"Reachable: true",
+				"Room: Kitchen",
+			},
+		},
+		{
+			name:  "Test color temperature light",
+			index: 1,
+			expected: []string{
+				"Active: off",
+				"Brightness: 254",
+				"[gray]Hue: [gray]Not supported",
+				"[gray]Saturation: [gray]Not supported",
+				"Color temperature: 366 mireds (2732K)",
+				"Color mode: ct",
+				"Reachable: true",
+				"Room: Kitchen",
+			},
+		},
+		{
+			name:  "Test dimmable light",
+			index: 2,
+			expected: []string{
+				"Active: off",
+				"Brightness: 254",
+				"[gray]Hue: [gray]Not supported",
+				"[gray]Saturation: [gray]Not supported",
+				"[gray]Color temperature: [gray]Not supported",
+				"Reachable: true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ui.showLight(tt.index)
+			if diff := cmp.Diff(tt.expected, items(ui.lightDetails)); diff != "" {
+				t.Errorf("Unexpected details (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPages(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	ui, _ := testTUI(t, server)
+	capture := ui.ui.GetInputCapture()
+
+	tests := []struct {
+		name     string
+		key      tcell.Key
+		r        rune
+		expected string
+		consumed bool
+	}{
+		{name: "Test number", key: tcell.KeyRune, r: '4', expected: pageScenes, consumed: true},
+		{name: "Test tab", key: tcell.KeyTab, expected: pageLights, consumed: true},
+		{name: "Test backtab", key: tcell.KeyBacktab, expected: pageScenes, consumed: true},
+		{name: "Test unknown page", key: tcell.KeyRune, r: '5', expected: pageScenes},
+		{name: "Test other keys", key: tcell.KeyRune, r: 'a', expected: pageScenes},
+		{name: "Test rooms", key: tcell.KeyRune, r: '2', expected: pageRooms, consumed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := capture(tcell.NewEventKey(tt.key, tt.r, tcell.ModNone))
+			if consumed := event == nil; consumed != tt.consumed {
+				t.Errorf("Expected the key to be consumed: %v but got %v", tt.consumed, consumed)
+			}
+
+			if page, _ := ui.pages.GetFrontPage(); page != tt.expected {
+				t.Errorf("Expected page %s but got %s", tt.expected, page)
+			}
+		})
+	}
+
+	// Numbers can be typed into sliders
+	ui.rooms.show(0)
+	press(ui.rooms.details, tcell.KeyRune, 'b')
+	if event := capture(tcell.NewEventKey(tcell.KeyRune, '1', tcell.ModNone)); event == nil {
+		t.Error("Expected the key to be passed to the slider")
+	}
+}
+
+func TestLightControls(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	ui, updates := testTUI(t, server)
+	ui.showLight(1)
+
+	// Toggle the light on with its shortcut
+	press(ui.lightDetails, tcell.KeyRune, 'a')
+	apply(t, updates)
+
+	if !server.Bridge.Datastore().Lights["2"].State.On {
+		t.Fatal("Expected the light to be turned on")
+	}
+
+	if text, _ := ui.lightDetails.GetItemText(0); text != "Active" {
+		t.Errorf("Expected the details to be shown but got %s", text)
+	}
+
+	if _, state := ui.lightDetails.GetItemText(0); state != "on" {
+		t.Errorf("Expected the light to be shown as on but got %s", state)
+	}
+
+	// The temperature slider is limited to the light's range
+	press(ui.lightDetails, tcell.KeyRune, 't')
+	_, slider := ui.footer.GetFrontPage()
+	press(slider, tcell.KeyPgDn, 0)
+	apply(t, updates)
+
+	press(slider, tcell.KeyLeft, 0)
+	apply(t, updates)
+
+	state := server.Bridge.Datastore().Lights["2"].State
+	if state.Temperature != 153 {
+		t.Errorf("Expected the temperature to be set to 153 but got %d", state.Temperature)
+	}
+
+	if _, text := ui.lightDetails.GetItemText(4); text != "153 mireds (6536K)" {
+		t.Errorf("Expected the new temperature to be shown but got %s", text)
+	}
+
+	// Closing the slider returns to the details
+	press(slider, tcell.KeyEscape, 0)
+	if name, _ := ui.footer.GetFrontPage(); name != footerStatus {
+		t.Errorf("Expected the slider to be closed but got %s", name)
+	}
+
+	// Errors are shown in the status line
+	ui.submit(2, api.NewStateBuilder().Temperature(300))
+	apply(t, updates)
+	if ui.status.GetText(true) == "" {
+		t.Error("Expected the unsupported change to be reported")
+	}
+}
@@ -3,8 +3,10 @@ module github.com/alejandro-angulo/hugh
 go 1.15
 
 require (
-	github.com/gdamore/tcell v1.4.0 // indirect
+	github.com/gdamore/tcell/v2 v2.0.1-0.20201017141208-acf90d56d591
 	github.com/google/go-cmp v0.5.4
 	github.com/grandcat/zeroconf v1.0.0
-	github.com/rivo/tview v0.0.0-20210217110421-8a8f78a6dd01 // indirect
+	github.com/pion/dtls/v2 v2.2.7
+	github.com/rivo/tview v0.0.0-20210217110421-8a8f78a6dd01
+	gopkg.in/yaml.v3 v3.0.1
 )
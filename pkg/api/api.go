@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +20,9 @@ import (
 // MulticastBrowser defines the interface required for the mDNS client
 type MulticastBrowser interface {
 	// Browse finds mDNS services of a give type in a given domain.
+	// Entries are sent in the background until the context is done. Browsers should close the channel once
+	// they're done sending (like zeroconf's resolver). Discover stops reading when the context is done either
+	// way, so sends must not block past the context.
 	Browse(ctx context.Context, service, domain string, entries chan<- *zeroconf.ServiceEntry) error
 }
 
@@ -33,6 +37,12 @@ type API struct {
 	// The number of seconds to wait for a Hue Bridge to be discovered
 	TimeoutSeconds int
 
+	// Schedules requests so bridges aren't flooded with commands (requests are sent immediately if nil)
+	RateLimiter *RateLimiter
+
+	// Retries requests that fail for transient reasons (requests are only sent once if nil)
+	Retry *RetryPolicy
+
 	// TODO: Keep a slice of (pointers to) Bridges?
 }
 
@@ -43,9 +53,19 @@ type APIError struct {
 	Description string `json:"description"`
 }
 
+func (err APIError) Error() string {
+	return fmt.Sprintf(
+		"Bridge returned an error. Error{type:`%d`, address:`%s`, description:`%s`}",
+		err.Type,
+		err.Address,
+		err.Description,
+	)
+}
+
 // ConnectSuccess represents the success dictionary from the Connect response
 type ConnectSuccess struct {
-	Username string `json:"username"`
+	Username  string `json:"username"`
+	ClientKey string `json:"clientkey"`
 }
 
 // ConnectResponse represents the API's response structure
@@ -61,8 +81,17 @@ type Bridge struct {
 	Model string `json:"model"`
 	IP    net.IP `json:"internalipaddress"` // TODO: Store v4 and v6 IPs as separate attributes?
 
+	// The port the bridge's API is served on (the default HTTP port is used when zero)
+	Port int `json:"port,omitempty"`
+
 	// The username to use when communicating with this brige
 	Username string `json:"-"`
+
+	// The key used to encrypt entertainment streams (see StartStreaming)
+	ClientKey string `json:"-"`
+
+	// Serves reads from memory when set (see EnableCache)
+	Cache *Cache `json:"-"`
 }
 
 // ErrBodyLengthTooLong is returned when the API's body is longer than expected
@@ -80,69 +109,114 @@ func parseServiceEntryText(entry *zeroconf.ServiceEntry) map[string]string {
 	return data
 }
 
+// httpsPort is the port advertised by real bridges (which serve the API over HTTP on the default port too)
+const httpsPort = 443
+
+// servicePort returns the port to send API requests to for an mDNS entry
+// Anything advertising a port other than the bridge's HTTPS port (an emulator, for example) is assumed to
+// serve the API over HTTP on that port.
+func servicePort(entry *zeroconf.ServiceEntry) int {
+	if entry.Port == httpsPort {
+		return 0
+	}
+
+	return entry.Port
+}
+
 // Discover searches for Phillips Hue bridges on the local network using mDNS
+// Progress is logged with the standard logger (to stderr by default).
 func (api *API) Discover() ([]Bridge, error) {
 	var bridges []Bridge
 	log.Println("Scanning network for Hue bridges...")
 
+	waitTime := time.Second * time.Duration(api.TimeoutSeconds)
+	ctx, cancel := context.WithTimeout(context.Background(), waitTime)
+	defer cancel()
+
 	entries := make(chan *zeroconf.ServiceEntry)
+	done := make(chan struct{})
 	go func(results <-chan *zeroconf.ServiceEntry) {
-		for entry := range results {
+		defer close(done)
+
+		for {
+			var entry *zeroconf.ServiceEntry
+			select {
+			case entry = <-results:
+			case <-ctx.Done():
+				return
+			}
+
+			// The browser is done
+			if entry == nil {
+				return
+			}
+
 			textData := parseServiceEntryText(entry)
 			newBridge := Bridge{
 				API:   api,
 				ID:    textData["bridgeid"],
 				Model: textData["modelid"],
 				IP:    entry.AddrIPv4[0], // Assume first item in slice is what we want
-
+				Port:  servicePort(entry),
 			}
 			bridges = append(bridges, newBridge)
-			fmt.Printf("Found Hue bridge at %s (ID: `%s` Model: `%s`)\n", newBridge.IP.String(), newBridge.ID, newBridge.Model)
+			log.Printf("Found Hue bridge at %s (ID: `%s` Model: `%s`)", newBridge.host(), newBridge.ID, newBridge.Model)
 		}
 	}(entries)
 
-	waitTime := time.Second * time.Duration(api.TimeoutSeconds)
-	ctx, cancel := context.WithTimeout(context.Background(), waitTime)
-	defer cancel()
 	err := api.Browser.Browse(ctx, "_hue._tcp", "local", entries)
 	if err != nil {
 		return nil, err
 	}
 
-	<-ctx.Done()
+	// Bridges are collected until the browser closes entries or the context is done
+	<-done
 
 	return bridges, nil
 }
 
 // Connect associates with a Phillips Hue Bridge
 // Returns the user ID  and sets the Bridge's Username attribute if sucessful
+// A client key for entertainment streaming is requested too and stored in the Bridge's ClientKey attribute.
 func (bridge *Bridge) Connect() (string, error) {
-	url := fmt.Sprintf("http://%s/api", bridge.IP.String())
+	url := fmt.Sprintf("http://%s/api", bridge.host())
 
 	hostname, err := os.Hostname()
 	if err != nil {
 		return "", err
 	}
 
-	payload, err := json.Marshal(map[string]string{
-		"devicetype": fmt.Sprintf("hugh#%s", hostname),
+	payload, err := json.Marshal(map[string]interface{}{
+		"devicetype":        fmt.Sprintf("hugh#%s", hostname),
+		"generateclientkey": true,
 	})
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := bridge.API.Client.Post(url, "application/json", bytes.NewBuffer(payload))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := bridge.API.do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	body := []ConnectResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&body)
+	data, err := readResponse(resp)
 	if err != nil {
 		return "", err
 	}
 
+	body := []ConnectResponse{}
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return "", newResponseError(resp, data, err)
+	}
+
 	// Only one item is expected (even though the API returns an array)
 	if len(body) != 1 {
 		return "", ErrBodyLengthTooLong
@@ -152,15 +226,173 @@ func (bridge *Bridge) Connect() (string, error) {
 	username := respData.Success.Username
 
 	if username == "" {
-		return "", fmt.Errorf(
-			"Failed to associate with bridge. Error{type:`%d`, address:`%s`, description:`%s`}",
-			respData.Error.Type,
-			respData.Error.Address,
-			respData.Error.Description,
-		)
+		return "", fmt.Errorf("Failed to associate with bridge: %w", respData.Error)
 	}
 
 	bridge.Username = respData.Success.Username
+	bridge.ClientKey = respData.Success.ClientKey
 
 	return respData.Success.Username, nil
 }
+
+// do sends a request, retrying it according to the API's retry policy
+func (api *API) do(req *http.Request) (*http.Response, error) {
+	if api.Retry != nil {
+		return api.Retry.send(req, api.doOnce)
+	}
+
+	return api.doOnce(req)
+}
+
+// doOnce sends a request once the rate limiter allows it
+// The request's context determines its priority (see WithPriority).
+func (api *API) doOnce(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if api.RateLimiter != nil && !rateLimitReserved(ctx) {
+		err := api.RateLimiter.Wait(ctx, classifyRequest(req), priorityFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return api.Client.Do(req)
+}
+
+// host returns the address requests are sent to
+func (bridge *Bridge) host() string {
+	if bridge.Port == 0 {
+		return bridge.IP.String()
+	}
+
+	return net.JoinHostPort(bridge.IP.String(), strconv.Itoa(bridge.Port))
+}
+
+// url returns the URL of a resource owned by the bridge's user
+func (bridge *Bridge) url(path string) string {
+	return fmt.Sprintf("http://%s/api/%s%s", bridge.host(), bridge.Username, path)
+}
+
+// updateResponse represents a single item in the API's response to a PUT request
+// Exactly one of the attributes is set.
+type updateResponse struct {
+	Error   *APIError                  `json:"error"`
+	Success map[string]json.RawMessage `json:"success"`
+}
+
+// get retrieves a resource owned by the bridge's user and decodes it into v
+func (bridge *Bridge) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bridge.url(path), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := bridge.API.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return decodeResource(resp, v)
+}
+
+// put sends a JSON payload to a resource owned by the bridge's user
+// The values the bridge reports as successfully updated are returned keyed by their address (for
+// example `/lights/1/state/bri`). The first error reported by the bridge is returned if there are any.
+func (bridge *Bridge) put(ctx context.Context, path string, payload interface{}) (map[string]json.RawMessage, error) {
+	return bridge.send(ctx, http.MethodPut, path, payload)
+}
+
+// post creates a resource owned by the bridge's user from a JSON payload
+// The values the bridge reports are returned like they are by put (`id` holds the new resource's ID).
+func (bridge *Bridge) post(ctx context.Context, path string, payload interface{}) (map[string]json.RawMessage, error) {
+	return bridge.send(ctx, http.MethodPost, path, payload)
+}
+
+// send sends a JSON payload to a resource owned by the bridge's user with the given method
+func (bridge *Bridge) send(ctx context.Context, method, path string, payload interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, bridge.url(path), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := bridge.API.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err = readResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		// Nothing to reconcile
+		return map[string]json.RawMessage{}, nil
+	}
+
+	body := []updateResponse{}
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, newResponseError(resp, data, err)
+	}
+
+	updated := map[string]json.RawMessage{}
+	var firstErr error
+	for _, item := range body {
+		if item.Error != nil && firstErr == nil {
+			firstErr = *item.Error
+		}
+
+		for address, value := range item.Success {
+			updated[address] = value
+		}
+	}
+
+	return updated, firstErr
+}
+
+// deleteResponse represents a single item in the API's response to a DELETE request
+// Successes are plain strings (`/schedules/1 deleted` for example) so only errors are decoded.
+type deleteResponse struct {
+	Error *APIError `json:"error"`
+}
+
+// delete removes a resource owned by the bridge's user
+func (bridge *Bridge) delete(ctx context.Context, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, bridge.url(path), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := bridge.API.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := readResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	body := []deleteResponse{}
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return newResponseError(resp, data, err)
+	}
+
+	for _, item := range body {
+		if item.Error != nil {
+			return *item.Error
+		}
+	}
+
+	return nil
+}
@@ -107,6 +107,8 @@ func TestDiscover(t *testing.T) {
 			api := NewTestAPI(
 				RoundTripFunc(DefaultRoundTrip),
 				func(ctx context.Context, service, domain string, entries chan<- *zeroconf.ServiceEntry) error {
+					defer close(entries)
+
 					for _, data := range tt.bridgeData {
 						entries <- &zeroconf.ServiceEntry{
 							Text:     data.text,
@@ -131,6 +133,28 @@ func TestDiscover(t *testing.T) {
 		})
 	}
 
+	t.Run("Test discovery stops when the timeout expires", func(t *testing.T) {
+		api := NewTestAPI(
+			DefaultRoundTrip,
+			func(ctx context.Context, service, domain string, entries chan<- *zeroconf.ServiceEntry) error {
+				// Never closes entries
+				entries <- &zeroconf.ServiceEntry{
+					Text:     []string{"bridgeid=test", "modelid=foo"},
+					AddrIPv4: []net.IP{{127, 0, 0, 1}},
+				}
+				return nil
+			},
+		)
+
+		got, err := api.Discover()
+
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+
+		assertBridges(t, got, []Bridge{{API: api, ID: "test", Model: "foo", IP: []byte{127, 0, 0, 1}}})
+	})
+
 	t.Run("Test error is returned on mDNS browse failure", func(t *testing.T) {
 		expectedError := errors.New("Simulated failure")
 
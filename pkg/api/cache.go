@@ -0,0 +1,276 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// ResourceKind identifies a type of resource held in a Cache
+type ResourceKind string
+
+// Kinds of resources that are cached
+const (
+	ResourceLights  ResourceKind = "lights"
+	ResourceGroups  ResourceKind = "groups"
+	ResourceSensors ResourceKind = "sensors"
+)
+
+// Cache holds the lights, groups and sensors read from a bridge for a limited time
+// Reads made through a bridge with a cache are served from memory until the TTL expires. Successful
+// writes update the cached resources so they reflect the change.
+//
+// The cache doesn't listen for changes made elsewhere (by the Hue app or a switch, for example) and nothing
+// in this package invalidates it on events. Those changes only show up once the TTL expires unless
+// something that learns about them (an event stream, a watcher polling the bridge, etc.) calls Invalidate.
+//
+// A nil *Cache is valid and caches nothing. A Cache is safe for concurrent use.
+type Cache struct {
+	// How long resources are served from memory
+	TTL time.Duration
+
+	mu      sync.Mutex
+	now     func() time.Time
+	fetched map[ResourceKind]time.Time
+	lights  map[string]Light
+	groups  map[string]Group
+	sensors map[string]Sensor
+}
+
+// NewCache creates an empty cache
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		TTL:     ttl,
+		now:     time.Now,
+		fetched: map[ResourceKind]time.Time{},
+	}
+}
+
+// EnableCache makes the bridge serve reads from a cache with the given TTL
+func (bridge *Bridge) EnableCache(ttl time.Duration) {
+	bridge.Cache = NewCache(ttl)
+}
+
+// Invalidate drops a kind of resource so the next read goes to the bridge
+func (cache *Cache) Invalidate(kind ResourceKind) {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.fetched, kind)
+}
+
+// InvalidateAll drops every cached resource
+func (cache *Cache) InvalidateAll() {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.fetched = map[ResourceKind]time.Time{}
+}
+
+// fresh returns true if a kind of resource was fetched within the TTL (must be called with the lock held)
+func (cache *Cache) fresh(kind ResourceKind) bool {
+	fetched, ok := cache.fetched[kind]
+	return ok && cache.now().Sub(fetched) < cache.TTL
+}
+
+func (cache *Cache) cachedLights() (map[string]Light, bool) {
+	if cache == nil {
+		return nil, false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if !cache.fresh(ResourceLights) {
+		return nil, false
+	}
+
+	lights := make(map[string]Light, len(cache.lights))
+	for id, light := range cache.lights {
+		lights[id] = light
+	}
+
+	return lights, true
+}
+
+func (cache *Cache) storeLights(lights map[string]Light) {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.lights = make(map[string]Light, len(lights))
+	for id, light := range lights {
+		cache.lights[id] = light
+	}
+	cache.fetched[ResourceLights] = cache.now()
+}
+
+// storeLightState updates the state of a cached light after a successful write
+func (cache *Cache) storeLightState(id string, state LightState) {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if light, ok := cache.lights[id]; ok {
+		light.State = state
+		cache.lights[id] = light
+	}
+}
+
+func (cache *Cache) cachedGroups() (map[string]Group, bool) {
+	if cache == nil {
+		return nil, false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if !cache.fresh(ResourceGroups) {
+		return nil, false
+	}
+
+	groups := make(map[string]Group, len(cache.groups))
+	for id, group := range cache.groups {
+		groups[id] = group.clone()
+	}
+
+	return groups, true
+}
+
+func (cache *Cache) storeGroups(groups map[string]Group) {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.groups = make(map[string]Group, len(groups))
+	for id, group := range groups {
+		cache.groups[id] = group.clone()
+	}
+	cache.fetched[ResourceGroups] = cache.now()
+}
+
+// storeGroupState updates the state and action of a cached group after a successful write
+func (cache *Cache) storeGroupState(id string, state GroupState, action LightState) {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if group, ok := cache.groups[id]; ok {
+		group.State = state
+		group.Action = action
+		cache.groups[id] = group
+	}
+}
+
+func (cache *Cache) cachedSensors() (map[string]Sensor, bool) {
+	if cache == nil {
+		return nil, false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if !cache.fresh(ResourceSensors) {
+		return nil, false
+	}
+
+	sensors := make(map[string]Sensor, len(cache.sensors))
+	for id, sensor := range cache.sensors {
+		sensors[id] = sensor.clone()
+	}
+
+	return sensors, true
+}
+
+func (cache *Cache) storeSensors(sensors map[string]Sensor) {
+	if cache == nil {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.sensors = make(map[string]Sensor, len(sensors))
+	for id, sensor := range sensors {
+		cache.sensors[id] = sensor.clone()
+	}
+	cache.fetched[ResourceSensors] = cache.now()
+}
+
+// clone returns a copy of a group that shares no memory with it (except the bridge)
+// Cached groups are cloned when they are stored and read so callers can't modify the cache.
+func (group Group) clone() Group {
+	group.Lights = cloneStrings(group.Lights)
+	group.Sensors = cloneStrings(group.Sensors)
+	if group.Stream != nil {
+		stream := *group.Stream
+		group.Stream = &stream
+	}
+
+	return group
+}
+
+// clone returns a copy of a sensor that shares no memory with it (except the bridge)
+func (sensor Sensor) clone() Sensor {
+	sensor.State = cloneValues(sensor.State)
+	sensor.Config = cloneValues(sensor.Config)
+
+	return sensor
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	return append([]string{}, values...)
+}
+
+// cloneValues deep copies decoded JSON values so nested objects and arrays aren't shared with the cache
+func cloneValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	cloned := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		cloned[key] = cloneValue(value)
+	}
+
+	return cloned
+}
+
+func cloneValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return cloneValues(value)
+	case []interface{}:
+		cloned := make([]interface{}, len(value))
+		for i, item := range value {
+			cloned[i] = cloneValue(item)
+		}
+
+		return cloned
+	default:
+		return value
+	}
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newCountingAPI(requests map[string]int) *API {
+	return NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		key := req.Method + " " + req.URL.Path
+		requests[key]++
+
+		var json string
+		switch key {
+		case "GET /api/user/lights":
+			json = `{"1": {"name": "Desk", "type": "Dimmable light", "state": {"on": false, "bri": 100}}}`
+		case "GET /api/user/groups":
+			json = `{"1": {"name": "Office", "lights": ["1"], "type": "Room", "state": {"all_on": false, "any_on": false}}}`
+		case "GET /api/user/sensors":
+			json = `{"1": {"name": "Daylight", "type": "Daylight", "state": {"daylight": true}, "config": {"offsets": {"sunset": [30]}}}}`
+		default:
+			json = `[]`
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(json))),
+		}, nil
+	}, DefaultBrowse)
+}
+
+func TestCache(t *testing.T) {
+	requests := map[string]int{}
+	bridge := Bridge{
+		IP:       []byte{127, 0, 0, 1},
+		Username: "user",
+		API:      newCountingAPI(requests),
+	}
+	bridge.EnableCache(time.Minute)
+
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	bridge.Cache.now = func() time.Time { return now }
+
+	getLight := func() Light {
+		t.Helper()
+
+		lights, err := bridge.GetLights()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if len(lights) != 1 {
+			t.Fatalf("Expected 1 light but got %d", len(lights))
+		}
+
+		return lights[0]
+	}
+
+	t.Run("Test reads within TTL are served from memory", func(t *testing.T) {
+		getLight()
+		getLight()
+
+		if requests["GET /api/user/lights"] != 1 {
+			t.Errorf("Expected 1 request but got %d", requests["GET /api/user/lights"])
+		}
+	})
+
+	t.Run("Test writes update the cache", func(t *testing.T) {
+		light := getLight()
+		err := light.ToggleLight()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if cached := getLight(); !cached.State.On {
+			t.Error("Expected cached light to be on")
+		}
+
+		if requests["GET /api/user/lights"] != 1 {
+			t.Errorf("Expected 1 request but got %d", requests["GET /api/user/lights"])
+		}
+	})
+
+	t.Run("Test expired reads go to the bridge", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+
+		if light := getLight(); light.State.On {
+			t.Error("Expected light to be refreshed from the bridge")
+		}
+
+		if requests["GET /api/user/lights"] != 2 {
+			t.Errorf("Expected 2 requests but got %d", requests["GET /api/user/lights"])
+		}
+	})
+
+	t.Run("Test group writes invalidate lights", func(t *testing.T) {
+		groups, err := bridge.GetGroups()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		err = groups[0].SetOn(true)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		groups, err = bridge.GetGroups()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if !groups[0].State.AllOn {
+			t.Error("Expected cached group to be on")
+		}
+
+		if requests["GET /api/user/groups"] != 1 {
+			t.Errorf("Expected 1 group request but got %d", requests["GET /api/user/groups"])
+		}
+
+		getLight()
+		if requests["GET /api/user/lights"] != 3 {
+			t.Errorf("Expected 3 light requests but got %d", requests["GET /api/user/lights"])
+		}
+	})
+
+	t.Run("Test light writes invalidate groups", func(t *testing.T) {
+		light := getLight()
+		err := light.ToggleLight()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		_, err = bridge.GetGroups()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if requests["GET /api/user/groups"] != 2 {
+			t.Errorf("Expected 2 group requests but got %d", requests["GET /api/user/groups"])
+		}
+	})
+
+	t.Run("Test cached resources can't be modified", func(t *testing.T) {
+		groups, err := bridge.GetGroups()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		groups[0].Lights[0] = "2"
+
+		sensors, err := bridge.GetSensors()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		sensors[0].State["daylight"] = false
+		sensors[0].Config["offsets"].(map[string]interface{})["sunset"].([]interface{})[0] = 60.0
+
+		groups, err = bridge.GetGroups()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if groups[0].Lights[0] != "1" {
+			t.Errorf("Expected cached group to have light 1 but got %s", groups[0].Lights[0])
+		}
+
+		sensors, err = bridge.GetSensors()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if sensors[0].State["daylight"] != true {
+			t.Errorf("Expected cached sensor to be in daylight but got %v", sensors[0].State["daylight"])
+		}
+
+		expectedConfig := map[string]interface{}{"offsets": map[string]interface{}{"sunset": []interface{}{30.0}}}
+		if diff := cmp.Diff(expectedConfig, sensors[0].Config); diff != "" {
+			t.Errorf("Cached sensor config mismatch (-want +got):\n%s", diff)
+		}
+
+		if requests["GET /api/user/groups"] != 2 {
+			t.Errorf("Expected 2 group requests but got %d", requests["GET /api/user/groups"])
+		}
+	})
+
+	t.Run("Test invalidation", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := bridge.GetSensors(); err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		}
+
+		bridge.Cache.Invalidate(ResourceSensors)
+
+		if _, err := bridge.GetSensors(); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if requests["GET /api/user/sensors"] != 2 {
+			t.Errorf("Expected 2 requests but got %d", requests["GET /api/user/sensors"])
+		}
+	})
+}
+
+func TestNoCache(t *testing.T) {
+	requests := map[string]int{}
+	bridge := Bridge{
+		IP:       []byte{127, 0, 0, 1},
+		Username: "user",
+		API:      newCountingAPI(requests),
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := bridge.GetLights(); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+
+	bridge.Cache.Invalidate(ResourceLights)
+
+	if requests["GET /api/user/lights"] != 2 {
+		t.Errorf("Expected 2 requests but got %d", requests["GET /api/user/lights"])
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Feature represents something a light may (or may not) be capable of
+type Feature uint
+
+// Features that can be checked with `Light.Supports`
+const (
+	FeatureOnOff Feature = 1 << iota
+	FeatureBrightness
+	FeatureColorTemperature
+	FeatureColor
+	FeatureStreaming
+)
+
+var featureNames = map[Feature]string{
+	FeatureOnOff:            "on/off",
+	FeatureBrightness:       "brightness",
+	FeatureColorTemperature: "color temperature",
+	FeatureColor:            "color",
+	FeatureStreaming:        "entertainment streaming",
+}
+
+func (feature Feature) String() string {
+	var names []string
+	for _, candidate := range []Feature{FeatureOnOff, FeatureBrightness, FeatureColorTemperature, FeatureColor, FeatureStreaming} {
+		if feature&candidate != 0 {
+			names = append(names, featureNames[candidate])
+		}
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// Has returns true if every feature in other is also in feature
+func (feature Feature) Has(other Feature) bool {
+	return feature&other == other
+}
+
+// Light types reported by the bridge
+const (
+	LightTypeOnOff            = "On/Off light"
+	LightTypeOnOffPlug        = "On/Off plug-in unit"
+	LightTypeDimmable         = "Dimmable light"
+	LightTypeColorTemperature = "Color temperature light"
+	LightTypeColor            = "Color light"
+	LightTypeExtendedColor    = "Extended color light"
+)
+
+var lightTypeFeatures = map[string]Feature{
+	LightTypeOnOff:            FeatureOnOff,
+	LightTypeOnOffPlug:        FeatureOnOff,
+	LightTypeDimmable:         FeatureOnOff | FeatureBrightness,
+	LightTypeColorTemperature: FeatureOnOff | FeatureBrightness | FeatureColorTemperature,
+	LightTypeColor:            FeatureOnOff | FeatureBrightness | FeatureColor,
+	LightTypeExtendedColor:    FeatureOnOff | FeatureBrightness | FeatureColorTemperature | FeatureColor,
+}
+
+// ErrUnsupportedFeature is wrapped by errors returned when a light is asked to do something it can't
+var ErrUnsupportedFeature = errors.New("Feature not supported by light")
+
+// UnsupportedFeatureError is returned when a state change requires features a light lacks
+type UnsupportedFeatureError struct {
+	Light    string
+	Type     string
+	Required Feature
+}
+
+func (err *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("Light `%s` (%s) does not support %s", err.Light, err.Type, err.Required)
+}
+
+// Unwrap allows the error to be compared to ErrUnsupportedFeature with errors.Is
+func (err *UnsupportedFeatureError) Unwrap() error {
+	return ErrUnsupportedFeature
+}
+
+// Features derives the set of features a light supports
+// The light's type is used when it is known. The capabilities reported by the bridge are used to fill in
+// anything the type misses (third party lights don't always report a standard type).
+func (light *Light) Features() Feature {
+	features := lightTypeFeatures[light.Type] | FeatureOnOff
+	control := light.Capabilities.Control
+
+	if control.MinimumDim != 0 || control.MaxLumen != 0 {
+		features |= FeatureBrightness
+	}
+
+	if control.TemperatureRange.Supported() {
+		features |= FeatureColorTemperature | FeatureBrightness
+	}
+
+	if control.ColorGamutType != "" {
+		features |= FeatureColor | FeatureBrightness
+	}
+
+	if light.Capabilities.Streaming.Renderer {
+		features |= FeatureStreaming
+	}
+
+	return features
+}
+
+// Supports returns true if the light supports all of the given features
+func (light *Light) Supports(feature Feature) bool {
+	return light.Features().Has(feature)
+}
+
+// require returns an error describing the missing features if the light doesn't support all of the given features
+func (light *Light) require(feature Feature) error {
+	missing := feature &^ light.Features()
+	if missing == 0 {
+		return nil
+	}
+
+	return &UnsupportedFeatureError{
+		Light:    light.Name,
+		Type:     light.Type,
+		Required: missing,
+	}
+}
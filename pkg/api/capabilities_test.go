@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		light    Light
+		expected Feature
+	}{
+		{
+			name:     "Test on/off plug",
+			light:    Light{Type: LightTypeOnOffPlug},
+			expected: FeatureOnOff,
+		},
+		{
+			name:     "Test dimmable light",
+			light:    Light{Type: LightTypeDimmable},
+			expected: FeatureOnOff | FeatureBrightness,
+		},
+		{
+			name: "Test extended color light with streaming",
+			light: Light{
+				Type: LightTypeExtendedColor,
+				Capabilities: LightCapabilities{
+					Streaming: LightStreamingCapabilities{Renderer: true},
+				},
+			},
+			expected: FeatureOnOff | FeatureBrightness | FeatureColorTemperature | FeatureColor | FeatureStreaming,
+		},
+		{
+			name: "Test unknown type falls back on capabilities",
+			light: Light{
+				Type: "Some third party bulb",
+				Capabilities: LightCapabilities{
+					Control: LightCapabilitiesControl{
+						TemperatureRange: LightTemperatureRange{Minimum: 153, Maximum: 500},
+					},
+				},
+			},
+			expected: FeatureOnOff | FeatureBrightness | FeatureColorTemperature,
+		},
+		{
+			name: "Test unknown type with gamut",
+			light: Light{
+				Type: "Some third party strip",
+				Capabilities: LightCapabilities{
+					Control: LightCapabilitiesControl{ColorGamutType: "C"},
+				},
+			},
+			expected: FeatureOnOff | FeatureBrightness | FeatureColor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.light.Features()
+			if got != tt.expected {
+				t.Errorf("Expected features `%s` but got `%s`", tt.expected, got)
+			}
+
+			if !tt.light.Supports(tt.expected) {
+				t.Errorf("Expected light to support `%s`", tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnsupportedSetters(t *testing.T) {
+	api := NewTestAPI(DefaultRoundTrip, DefaultBrowse)
+	light := Light{
+		Name: "Desk",
+		Type: LightTypeDimmable,
+		Bridge: &Bridge{
+			IP:  []byte{127, 0, 0, 1},
+			API: api,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		setter func() error
+	}{
+		{name: "Test hue", setter: func() error { return light.SetHue(100) }},
+		{name: "Test saturation", setter: func() error { return light.SetSaturation(100) }},
+		{name: "Test CIE coordinates", setter: func() error { return light.SetCIECoords(CIECoord{0.3, 0.3}) }},
+		{name: "Test color temperature", setter: func() error { return light.SetColorTemperatureKelvin(2700) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.setter()
+			if !errors.Is(err, ErrUnsupportedFeature) {
+				t.Fatalf("Expected %v but got %v", ErrUnsupportedFeature, err)
+			}
+
+			var featureErr *UnsupportedFeatureError
+			if !errors.As(err, &featureErr) || featureErr.Light != "Desk" {
+				t.Errorf("Expected error to describe the light but got %v", err)
+			}
+		})
+	}
+
+	t.Run("Test supported setter sends request", func(t *testing.T) {
+		light.Bridge.API = NewTestAPI(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}, DefaultBrowse)
+
+		err := light.SetBrightness(100)
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+
+		if light.State.Brightness != 100 {
+			t.Errorf("Expected brightness to be updated to 100 but got %d", light.State.Brightness)
+		}
+	})
+}
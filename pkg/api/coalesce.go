@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// PendingState is the eventual result of a state update submitted to a Coalescer
+type PendingState struct {
+	done  chan struct{}
+	state LightState
+	err   error
+}
+
+func newPendingState() *PendingState {
+	return &PendingState{done: make(chan struct{})}
+}
+
+func (pending *PendingState) resolve(state LightState, err error) {
+	pending.state = state
+	pending.err = err
+	close(pending.done)
+}
+
+// Done returns a channel that is closed once the bridge has responded
+func (pending *PendingState) Done() <-chan struct{} {
+	return pending.done
+}
+
+// Wait blocks until the bridge has responded
+// The light's state after the (merged) update is returned along with any error. An empty state is
+// returned if the update was rejected before being sent.
+func (pending *PendingState) Wait() (LightState, error) {
+	<-pending.done
+	return pending.state, pending.err
+}
+
+// batch holds the updates merged for a light while waiting to be sent
+type batch struct {
+	ctx     context.Context
+	light   *Light // A copy of the most recently submitted light that the update is sent with
+	update  LightStateUpdate
+	pending []*PendingState
+}
+
+// coalescedLight tracks the updates for a single light
+type coalescedLight struct {
+	next     *batch
+	flushing bool
+}
+
+// Coalescer merges rapid successive state updates to the same light
+// Updates submitted while an earlier update is waiting on the rate limiter (or still being sent) are
+// merged into a single request. Attributes are merged so the last writer wins while deltas are added
+// together. Every caller's PendingState is resolved with the result of the request that carried its update.
+//
+// The light passed to Submit isn't modified. Requests are sent with a copy of it and callers get the light's
+// new state from PendingState.Wait.
+type Coalescer struct {
+	mu     sync.Mutex
+	lights map[string]*coalescedLight
+}
+
+// NewCoalescer creates a coalescer with nothing pending
+func NewCoalescer() *Coalescer {
+	return &Coalescer{
+		lights: map[string]*coalescedLight{},
+	}
+}
+
+// Submit queues a state update for a light
+// The update is validated immediately. The context is used when sending the request (the most recently
+// submitted context is used for a merged request, so a merged request is only abandoned once the context of
+// the last update merged into it is done).
+func (coalescer *Coalescer) Submit(ctx context.Context, light *Light, update LightStateUpdate) *PendingState {
+	pending := newPendingState()
+
+	err := light.require(update.requiredFeatures())
+	if err == nil {
+		err = update.Validate(light)
+	}
+	if err != nil {
+		pending.resolve(LightState{}, err)
+		return pending
+	}
+
+	key := fmt.Sprintf("%s/%s", light.Bridge.host(), light.ID)
+
+	coalescer.mu.Lock()
+	defer coalescer.mu.Unlock()
+
+	entry, ok := coalescer.lights[key]
+	if !ok {
+		entry = &coalescedLight{}
+		coalescer.lights[key] = entry
+	}
+
+	if entry.next == nil {
+		entry.next = &batch{}
+	}
+
+	snapshot := *light
+	entry.next.ctx = ctx
+	entry.next.light = &snapshot
+	entry.next.update = entry.next.update.merge(update, snapshot.TemperatureRange())
+	entry.next.pending = append(entry.next.pending, pending)
+
+	if !entry.flushing {
+		entry.flushing = true
+		go coalescer.flush(key, entry)
+	}
+
+	return pending
+}
+
+// flush sends a light's batches until there are no more
+func (coalescer *Coalescer) flush(key string, entry *coalescedLight) {
+	for {
+		err := coalescer.wait(entry)
+
+		coalescer.mu.Lock()
+		next := entry.next
+		entry.next = nil
+		coalescer.mu.Unlock()
+
+		if err == nil {
+			err = next.light.SetStateContext(withReservation(next.ctx), next.update)
+		}
+
+		for _, pending := range next.pending {
+			pending.resolve(next.light.State, err)
+		}
+
+		coalescer.mu.Lock()
+		if entry.next == nil {
+			entry.flushing = false
+			delete(coalescer.lights, key)
+			coalescer.mu.Unlock()
+			return
+		}
+		coalescer.mu.Unlock()
+	}
+}
+
+// wait waits on the rate limiter for a light's next batch
+// Updates keep being merged into the batch while waiting. If the context being waited with is done but a newer
+// update brought a context that isn't, the wait starts over with the newer context.
+func (coalescer *Coalescer) wait(entry *coalescedLight) error {
+	for {
+		coalescer.mu.Lock()
+		ctx := entry.next.ctx
+		limiter := entry.next.light.Bridge.API.RateLimiter
+		coalescer.mu.Unlock()
+
+		if limiter == nil {
+			return nil
+		}
+
+		err := limiter.Wait(ctx, ClassLight, priorityFromContext(ctx))
+
+		coalescer.mu.Lock()
+		newer := entry.next.ctx != ctx
+		coalescer.mu.Unlock()
+
+		if err == nil || ctx.Err() == nil || !newer {
+			return err
+		}
+	}
+}
+
+// merge combines two updates as if they were sent one after the other
+// Attributes set in next replace those in update. Deltas are added together and folded into a pending
+// absolute value if there is one (the bridge ignores deltas when an absolute value is set). A delta of 0 stops
+// transitions so it replaces pending values of its attribute instead of being folded into them.
+func (update LightStateUpdate) merge(next LightStateUpdate, tempRange LightTemperatureRange) LightStateUpdate {
+	merged := update
+
+	if next.On != nil {
+		merged.On = next.On
+	}
+
+	if next.Alert != nil {
+		merged.Alert = next.Alert
+	}
+
+	if next.Effect != nil {
+		merged.Effect = next.Effect
+	}
+
+	if next.TransitionTime != nil {
+		merged.TransitionTime = next.TransitionTime
+	}
+
+	if next.Brightness != nil {
+		merged.Brightness = next.Brightness
+		merged.BrightnessDelta = nil
+	} else if next.BrightnessDelta != nil && *next.BrightnessDelta == 0 {
+		merged.Brightness = nil
+		merged.BrightnessDelta = next.BrightnessDelta
+	} else if next.BrightnessDelta != nil {
+		if merged.Brightness != nil {
+			value := uint8(clamp(int64(*merged.Brightness)+int64(*next.BrightnessDelta), MinimumBrightness, MaximumBrightness))
+			merged.Brightness = &value
+		} else {
+			merged.BrightnessDelta = sumInt16(merged.BrightnessDelta, *next.BrightnessDelta, MaximumBrightnessDelta)
+		}
+	}
+
+	if next.Saturation != nil {
+		merged.Saturation = next.Saturation
+		merged.SaturationDelta = nil
+	} else if next.SaturationDelta != nil && *next.SaturationDelta == 0 {
+		merged.Saturation = nil
+		merged.SaturationDelta = next.SaturationDelta
+	} else if next.SaturationDelta != nil {
+		if merged.Saturation != nil {
+			value := uint8(clamp(int64(*merged.Saturation)+int64(*next.SaturationDelta), 0, MaximumSaturation))
+			merged.Saturation = &value
+		} else {
+			merged.SaturationDelta = sumInt16(merged.SaturationDelta, *next.SaturationDelta, MaximumSaturationDelta)
+		}
+	}
+
+	if next.Hue != nil {
+		merged.Hue = next.Hue
+		merged.HueDelta = nil
+	} else if next.HueDelta != nil && *next.HueDelta == 0 {
+		merged.Hue = nil
+		merged.HueDelta = next.HueDelta
+	} else if next.HueDelta != nil {
+		if merged.Hue != nil {
+			value := uint16((int64(*merged.Hue) + int64(*next.HueDelta)) & MaximumHue)
+			merged.Hue = &value
+		} else {
+			merged.HueDelta = sumInt32(merged.HueDelta, *next.HueDelta, MaximumHueDelta)
+		}
+	}
+
+	if next.Temperature != nil {
+		merged.Temperature = next.Temperature
+		merged.TemperatureDelta = nil
+	} else if next.TemperatureDelta != nil && *next.TemperatureDelta == 0 {
+		merged.Temperature = nil
+		merged.TemperatureDelta = next.TemperatureDelta
+	} else if next.TemperatureDelta != nil {
+		if merged.Temperature != nil {
+			value := uint16(clamp(int64(*merged.Temperature)+int64(*next.TemperatureDelta), int64(tempRange.Minimum), int64(tempRange.Maximum)))
+			merged.Temperature = &value
+		} else {
+			merged.TemperatureDelta = sumInt32(merged.TemperatureDelta, *next.TemperatureDelta, MaximumTemperatureDelta)
+		}
+	}
+
+	if next.CIECoords != nil {
+		merged.CIECoords = next.CIECoords
+		merged.CIEDelta = nil
+	} else if next.CIEDelta != nil && *next.CIEDelta == (CIECoord{}) {
+		merged.CIECoords = nil
+		merged.CIEDelta = next.CIEDelta
+	} else if next.CIEDelta != nil {
+		if merged.CIECoords != nil {
+			state := LightState{CIECoords: *merged.CIECoords}
+			state.apply(LightStateUpdate{CIEDelta: next.CIEDelta}, tempRange)
+			merged.CIECoords = &state.CIECoords
+		} else {
+			var delta CIECoord
+			if merged.CIEDelta != nil {
+				delta = *merged.CIEDelta
+			}
+			for i := range delta {
+				delta[i] = roundCIE(clampFloat(delta[i]+next.CIEDelta[i], -MaximumCIEDelta, MaximumCIEDelta))
+			}
+			merged.CIEDelta = &delta
+		}
+	}
+
+	return merged
+}
+
+func sumInt16(current *int16, delta int16, limit int64) *int16 {
+	sum := int64(delta)
+	if current != nil {
+		sum += int64(*current)
+	}
+
+	value := int16(clamp(sum, -limit, limit))
+	return &value
+}
+
+func sumInt32(current *int32, delta int32, limit int64) *int32 {
+	sum := int64(delta)
+	if current != nil {
+		sum += int64(*current)
+	}
+
+	value := int32(clamp(sum, -limit, limit))
+	return &value
+}
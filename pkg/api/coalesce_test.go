@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeUpdates(t *testing.T) {
+	tempRange := LightTemperatureRange{Minimum: 153, Maximum: 454}
+
+	tests := []struct {
+		name     string
+		first    *StateBuilder
+		second   *StateBuilder
+		expected *StateBuilder
+	}{
+		{
+			name:     "Test last writer wins",
+			first:    NewStateBuilder().On(false).Brightness(10).Hue(100),
+			second:   NewStateBuilder().On(true).Brightness(20),
+			expected: NewStateBuilder().On(true).Brightness(20).Hue(100),
+		},
+		{
+			name:     "Test deltas are added",
+			first:    NewStateBuilder().BrightnessDelta(200).HueDelta(-100),
+			second:   NewStateBuilder().BrightnessDelta(100).HueDelta(-100),
+			expected: NewStateBuilder().BrightnessDelta(254).HueDelta(-200),
+		},
+		{
+			name:     "Test delta is folded into absolute value",
+			first:    NewStateBuilder().Brightness(100).Temperature(450).CIECoords(0.5, 0.5),
+			second:   NewStateBuilder().BrightnessDelta(-20).TemperatureDelta(50).CIEDelta(0.1, -0.2),
+			expected: NewStateBuilder().Brightness(80).Temperature(454).CIECoords(0.6, 0.3),
+		},
+		{
+			name:     "Test absolute value replaces delta",
+			first:    NewStateBuilder().SaturationDelta(20),
+			second:   NewStateBuilder().Saturation(100),
+			expected: NewStateBuilder().Saturation(100),
+		},
+		{
+			name:     "Test stopping a transition replaces pending delta",
+			first:    NewStateBuilder().BrightnessDelta(50).HueDelta(100),
+			second:   NewStateBuilder().StopTransition(),
+			expected: NewStateBuilder().BrightnessDelta(0).HueDelta(100),
+		},
+		{
+			name:     "Test stopping a transition replaces pending value",
+			first:    NewStateBuilder().On(true).Brightness(100),
+			second:   NewStateBuilder().StopTransition(),
+			expected: NewStateBuilder().On(true).BrightnessDelta(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := tt.first.Build(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			second, err := tt.second.Build(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := tt.expected.Build(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := first.merge(second, tempRange)
+			if diff := cmp.Diff(expected, got); diff != "" {
+				t.Errorf("Merged update mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCoalescer(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	received := make(chan struct{})
+	release := make(chan struct{})
+
+	api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		bodies = append(bodies, string(data))
+		first := len(bodies) == 1
+		mu.Unlock()
+
+		if first {
+			received <- struct{}{}
+			<-release
+		}
+
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}, DefaultBrowse)
+
+	light := &Light{
+		ID:     "1",
+		Type:   LightTypeDimmable,
+		Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+	}
+
+	coalescer := NewCoalescer()
+	submit := func(builder *StateBuilder) *PendingState {
+		update, err := builder.Build(light)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return coalescer.Submit(context.Background(), light, update)
+	}
+
+	first := submit(NewStateBuilder().Brightness(10))
+	<-received
+
+	// These are sent together once the first request completes
+	rest := []*PendingState{
+		submit(NewStateBuilder().Brightness(20)),
+		submit(NewStateBuilder().Brightness(30)),
+		submit(NewStateBuilder().On(true)),
+	}
+	close(release)
+
+	state, err := first.Wait()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if state.Brightness != 10 {
+		t.Errorf("Expected first update to resolve with brightness 10 but got %d", state.Brightness)
+	}
+
+	for i, pending := range rest {
+		state, err := pending.Wait()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if state.Brightness != 30 || !state.On {
+			t.Errorf("Update #%d resolved with unexpected state %+v", i, state)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 requests but got %d: %v", len(bodies), bodies)
+	}
+
+	if !jsonEqual(t, bodies[1], `{"on":true,"bri":30}`) {
+		t.Errorf("Unexpected merged body %s", bodies[1])
+	}
+
+	// Requests are sent with a copy of the light
+	if light.State.Brightness != 0 || light.State.On {
+		t.Errorf("Expected the submitted light to be left alone but got %+v", light.State)
+	}
+
+	t.Run("Test invalid update is resolved immediately", func(t *testing.T) {
+		hue := uint16(100)
+		pending := coalescer.Submit(context.Background(), light, LightStateUpdate{Hue: &hue})
+
+		_, err := pending.Wait()
+		if !errors.Is(err, ErrUnsupportedFeature) {
+			t.Errorf("Expected %v but got %v", ErrUnsupportedFeature, err)
+		}
+	})
+}
+
+func TestCoalescerWaitsOnRateLimiter(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+
+	api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		bodies = append(bodies, string(data))
+		mu.Unlock()
+
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}, DefaultBrowse)
+	api.RateLimiter = NewRateLimiter(RateLimit{PerSecond: 10, Burst: 1}, DefaultGroupRateLimit)
+
+	// Use up the burst so the coalescer has to wait
+	err := api.RateLimiter.Wait(context.Background(), ClassLight, PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	light := &Light{
+		ID:     "1",
+		Type:   LightTypeDimmable,
+		Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+	}
+
+	coalescer := NewCoalescer()
+	var pending []*PendingState
+	for brightness := 1; brightness <= 5; brightness++ {
+		update, err := NewStateBuilder().BrightnessDelta(brightness).Build(light)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pending = append(pending, coalescer.Submit(context.Background(), light, update))
+	}
+
+	for _, p := range pending {
+		if _, err := p.Wait(); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 request but got %d: %v", len(bodies), bodies)
+	}
+
+	if !jsonEqual(t, bodies[0], `{"bri_inc":15}`) {
+		t.Errorf("Unexpected merged body %s", bodies[0])
+	}
+}
+
+func TestCoalescerContexts(t *testing.T) {
+	api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}, DefaultBrowse)
+	api.RateLimiter = NewRateLimiter(RateLimit{PerSecond: 10, Burst: 1}, DefaultGroupRateLimit)
+
+	// Use up the burst so the coalescer has to wait
+	err := api.RateLimiter.Wait(context.Background(), ClassLight, PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	light := &Light{
+		ID:     "1",
+		Type:   LightTypeDimmable,
+		Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+	}
+
+	coalescer := NewCoalescer()
+	submit := func(ctx context.Context, brightness int) *PendingState {
+		update, err := NewStateBuilder().Brightness(brightness).Build(light)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return coalescer.Submit(ctx, light, update)
+	}
+
+	// The batch outlives the first caller's context as long as a later caller's context isn't done
+	ctx, cancel := context.WithCancel(context.Background())
+	first := submit(ctx, 10)
+	time.Sleep(10 * time.Millisecond) // Let the coalescer start waiting with the first context
+	second := submit(context.Background(), 20)
+	cancel()
+
+	for _, pending := range []*PendingState{first, second} {
+		state, err := pending.Wait()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if state.Brightness != 20 {
+			t.Errorf("Expected brightness 20 but got %d", state.Brightness)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"math"
+)
+
+// The range (in Kelvin) over which the CIE approximation of a color temperature is valid
+const (
+	minimumApproximateKelvin = 1667
+	maximumApproximateKelvin = 25000
+)
+
+// KelvinToMired converts a color temperature in Kelvin to mireds (micro reciprocal degrees)
+// A value of 0 Kelvin is converted to 0 mireds.
+func KelvinToMired(kelvin uint) uint {
+	if kelvin == 0 {
+		return 0
+	}
+
+	return uint(math.Round(1e6 / float64(kelvin)))
+}
+
+// MiredToKelvin converts a color temperature in mireds (micro reciprocal degrees) to Kelvin
+// A value of 0 mireds is converted to 0 Kelvin.
+func MiredToKelvin(mired uint) uint {
+	if mired == 0 {
+		return 0
+	}
+
+	return uint(math.Round(1e6 / float64(mired)))
+}
+
+// Supported returns true if the range describes a light that can set a color temperature
+// Lights that do not support the `ct` attribute report an empty range.
+func (tempRange LightTemperatureRange) Supported() bool {
+	return tempRange.Maximum != 0
+}
+
+// Clamp restricts a color temperature (in mireds) to the range
+func (tempRange LightTemperatureRange) Clamp(mired uint) uint {
+	if mired < tempRange.Minimum {
+		return tempRange.Minimum
+	}
+
+	if mired > tempRange.Maximum {
+		return tempRange.Maximum
+	}
+
+	return mired
+}
+
+// ClampKelvin restricts a color temperature (in Kelvin) to the range and returns the result in mireds
+func (tempRange LightTemperatureRange) ClampKelvin(kelvin uint) uint {
+	return tempRange.Clamp(KelvinToMired(kelvin))
+}
+
+// TemperatureRange returns the light's color temperature range
+// DefaultTemperatureRange is returned for a nil light or a light that doesn't report a range.
+func (light *Light) TemperatureRange() LightTemperatureRange {
+	if light != nil && light.Capabilities.Control.TemperatureRange.Supported() {
+		return light.Capabilities.Control.TemperatureRange
+	}
+
+	return DefaultTemperatureRange
+}
+
+// KelvinToCIE approximates a color temperature (in Kelvin) as coordinates in the CIE color space
+// The approximation follows the Planckian locus using the cubic splines from Kim et al. and is only
+// valid from 1667K to 25000K. Values outside of that range are clamped.
+func KelvinToCIE(kelvin uint) CIECoord {
+	temp := math.Max(minimumApproximateKelvin, math.Min(maximumApproximateKelvin, float64(kelvin)))
+
+	var x float64
+	if temp <= 4000 {
+		x = -0.2661239e9/math.Pow(temp, 3) - 0.2343589e6/math.Pow(temp, 2) + 0.8776956e3/temp + 0.179910
+	} else {
+		x = -3.0258469e9/math.Pow(temp, 3) + 2.1070379e6/math.Pow(temp, 2) + 0.2226347e3/temp + 0.240390
+	}
+
+	var y float64
+	switch {
+	case temp <= 2222:
+		y = -1.1063814*math.Pow(x, 3) - 1.34811020*math.Pow(x, 2) + 2.18555832*x - 0.20219683
+	case temp <= 4000:
+		y = -0.9549476*math.Pow(x, 3) - 1.37418593*math.Pow(x, 2) + 2.09137015*x - 0.16748867
+	default:
+		y = 3.0817580*math.Pow(x, 3) - 5.87338670*math.Pow(x, 2) + 3.75112997*x - 0.37001483
+	}
+
+	return CIECoord{roundCIE(x), roundCIE(y)}
+}
+
+// RGBToCIE converts an sRGB color to coordinates in the CIE color space
+// The conversion follows Philips' guidance for Hue lights: the channels are gamma corrected and mapped to
+// XYZ with the Wide RGB D65 matrix. Black (which has no chromaticity) is converted to the D65 white point.
+// The bridge restricts the coordinates to each light's gamut.
+func RGBToCIE(red, green, blue uint8) CIECoord {
+	linear := func(channel uint8) float64 {
+		value := float64(channel) / 255
+		if value > 0.04045 {
+			return math.Pow((value+0.055)/1.055, 2.4)
+		}
+
+		return value / 12.92
+	}
+
+	r, g, b := linear(red), linear(green), linear(blue)
+	x := r*0.664511 + g*0.154324 + b*0.162028
+	y := r*0.283881 + g*0.668433 + b*0.047685
+	z := r*0.000088 + g*0.072310 + b*0.986039
+
+	sum := x + y + z
+	if sum == 0 {
+		return CIECoord{0.3127, 0.329}
+	}
+
+	return CIECoord{roundCIE(x / sum), roundCIE(y / sum)}
+}
+
+// roundCIE rounds a CIE coordinate to the precision the bridge reports (4 decimal places)
+func roundCIE(value float64) float64 {
+	return math.Round(value*1e4) / 1e4
+}
+
+// SetColorTemperatureKelvin sets a light's color temperature
+// Lights that support the `ct` attribute are sent a value clamped to the light's temperature range.
+// Color lights without `ct` support are sent an approximation of the color temperature in the CIE
+// color space instead.
+func (light *Light) SetColorTemperatureKelvin(kelvin uint, options ...StateOption) error {
+	tempRange := light.Capabilities.Control.TemperatureRange
+
+	if tempRange.Supported() {
+		mired := tempRange.ClampKelvin(kelvin)
+		return light.setState(NewStateBuilder().Temperature(int(mired)).With(options...))
+	}
+
+	if light.Supports(FeatureColor) {
+		return light.SetCIECoords(KelvinToCIE(kelvin), options...)
+	}
+
+	return light.require(FeatureColorTemperature)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKelvinToMired(t *testing.T) {
+	tests := []struct {
+		name   string
+		kelvin uint
+		mired  uint
+	}{
+		{name: "Test zero", kelvin: 0, mired: 0},
+		{name: "Test coolest Hue temperature", kelvin: 6500, mired: 154},
+		{name: "Test warmest Hue temperature", kelvin: 2000, mired: 500},
+		{name: "Test rounding", kelvin: 2700, mired: 370},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := KelvinToMired(tt.kelvin)
+			if got != tt.mired {
+				t.Errorf("Expected %d mireds but got %d", tt.mired, got)
+			}
+		})
+	}
+}
+
+func TestMiredToKelvin(t *testing.T) {
+	tests := []struct {
+		name   string
+		mired  uint
+		kelvin uint
+	}{
+		{name: "Test zero", mired: 0, kelvin: 0},
+		{name: "Test minimum Hue temperature", mired: 153, kelvin: 6536},
+		{name: "Test maximum Hue temperature", mired: 500, kelvin: 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MiredToKelvin(tt.mired)
+			if got != tt.kelvin {
+				t.Errorf("Expected %dK but got %dK", tt.kelvin, got)
+			}
+		})
+	}
+}
+
+func TestTemperatureRangeClamp(t *testing.T) {
+	tempRange := LightTemperatureRange{Minimum: 153, Maximum: 454}
+
+	tests := []struct {
+		name     string
+		mired    uint
+		expected uint
+	}{
+		{name: "Test value below range", mired: 100, expected: 153},
+		{name: "Test value in range", mired: 300, expected: 300},
+		{name: "Test value above range", mired: 500, expected: 454},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tempRange.Clamp(tt.mired)
+			if got != tt.expected {
+				t.Errorf("Expected %d but got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestKelvinToCIE(t *testing.T) {
+	tests := []struct {
+		name     string
+		kelvin   uint
+		expected CIECoord
+	}{
+		{name: "Test warm white", kelvin: 2700, expected: CIECoord{0.4593, 0.4107}},
+		{name: "Test daylight", kelvin: 6500, expected: CIECoord{0.3135, 0.3237}},
+		{name: "Test value below valid range is clamped", kelvin: 1000, expected: KelvinToCIE(minimumApproximateKelvin)},
+		{name: "Test value above valid range is clamped", kelvin: 40000, expected: KelvinToCIE(maximumApproximateKelvin)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := KelvinToCIE(tt.kelvin)
+			if got != tt.expected {
+				t.Errorf("Expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRGBToCIE(t *testing.T) {
+	tests := []struct {
+		name             string
+		red, green, blue uint8
+		expected         CIECoord
+	}{
+		{name: "Test red", red: 255, expected: CIECoord{0.7006, 0.2993}},
+		{name: "Test green", green: 255, expected: CIECoord{0.1724, 0.7468}},
+		{name: "Test blue", blue: 255, expected: CIECoord{0.1355, 0.0399}},
+		{name: "Test white", red: 255, green: 255, blue: 255, expected: CIECoord{0.3227, 0.329}},
+		{name: "Test black", expected: CIECoord{0.3127, 0.329}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RGBToCIE(tt.red, tt.green, tt.blue)
+			if got != tt.expected {
+				t.Errorf("Expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetColorTemperatureKelvin(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities LightCapabilitiesControl
+		kelvin       uint
+		expectedBody string
+		expectedErr  error
+	}{
+		{
+			name: "Test temperature is clamped to light's range",
+			capabilities: LightCapabilitiesControl{
+				TemperatureRange: LightTemperatureRange{Minimum: 153, Maximum: 454},
+			},
+			kelvin:       2000,
+			expectedBody: `{"ct":454}`,
+		},
+		{
+			name: "Test color light without ct support is sent xy",
+			capabilities: LightCapabilitiesControl{
+				ColorGamutType: "A",
+			},
+			kelvin:       2700,
+			expectedBody: `{"xy":[0.4593,0.4107]}`,
+		},
+		{
+			name:         "Test dimmable light is not supported",
+			capabilities: LightCapabilitiesControl{},
+			kelvin:       2700,
+			expectedErr:  ErrUnsupportedFeature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				body = string(data)
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+				}, nil
+			}, DefaultBrowse)
+
+			light := Light{
+				Bridge: &Bridge{
+					IP:  []byte{127, 0, 0, 1},
+					API: api,
+				},
+				Capabilities: LightCapabilities{Control: tt.capabilities},
+			}
+
+			err := light.SetColorTemperatureKelvin(tt.kelvin)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected %v but got %v", tt.expectedErr, err)
+			}
+
+			if !jsonEqual(t, body, tt.expectedBody) {
+				t.Errorf("Expected body %s but got %s", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func jsonEqual(t *testing.T, got, want string) bool {
+	t.Helper()
+
+	if got == "" || want == "" {
+		return got == want
+	}
+
+	var gotData, wantData interface{}
+	if err := json.Unmarshal([]byte(got), &gotData); err != nil {
+		t.Fatalf("Failed to decode %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantData); err != nil {
+		t.Fatalf("Failed to decode %s: %v", want, err)
+	}
+
+	return cmp.Equal(gotData, wantData)
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v2"
+)
+
+// EntertainmentPort is the UDP port bridges accept entertainment streams on
+const EntertainmentPort = 2100
+
+// MaximumStreamRate is the most frames per second a bridge should be sent
+const MaximumStreamRate = 50
+
+// The most lights (v1) or channels (v2) a single frame can hold
+const (
+	maximumLightsPerFrame   = 10
+	maximumChannelsPerFrame = 20
+)
+
+// entertainmentConfigurationIDLength is the length of the UUID identifying an entertainment configuration
+const entertainmentConfigurationIDLength = 36
+
+// ErrNoClientKey is returned when streaming to a bridge without a client key (see Connect)
+var ErrNoClientKey = errors.New("Bridge has no client key")
+
+// ErrNotEntertainmentGroup is returned when streaming to a group that isn't an entertainment area
+var ErrNotEntertainmentGroup = errors.New("Group is not an entertainment area")
+
+// GroupStream represents the streaming state of an entertainment group
+type GroupStream struct {
+	ProxyMode string `json:"proxymode"`
+	ProxyNode string `json:"proxynode"`
+	Active    bool   `json:"active"`
+	Owner     string `json:"owner"` // The user streaming to the group (if active)
+}
+
+// ColorSpace identifies how colors in a frame are expressed
+type ColorSpace byte
+
+// Color spaces supported by HueStream frames
+const (
+	ColorSpaceRGB ColorSpace = 0x00
+	ColorSpaceXY  ColorSpace = 0x01 // CIE coordinates and brightness
+)
+
+// StreamColor represents the color of a light (HueStream v1) or channel (HueStream v2) in a frame
+// Values are red, green and blue for ColorSpaceRGB or x, y and brightness for ColorSpaceXY, scaled to
+// the full range of a uint16.
+type StreamColor struct {
+	ID     uint16
+	Values [3]uint16
+}
+
+// RGBColor creates the color of a light or channel from 8-bit red, green and blue values
+func RGBColor(id uint16, red, green, blue uint8) StreamColor {
+	// Multiplying by 257 maps 0xff to 0xffff
+	return StreamColor{ID: id, Values: [3]uint16{uint16(red) * 257, uint16(green) * 257, uint16(blue) * 257}}
+}
+
+// XYColor creates the color of a light or channel from CIE coordinates and a brightness (0 to 1)
+func XYColor(id uint16, coords CIECoord, brightness float64) StreamColor {
+	scale := func(value float64) uint16 {
+		return uint16(math.Round(clampFloat(value, 0, 1) * math.MaxUint16))
+	}
+
+	return StreamColor{ID: id, Values: [3]uint16{scale(coords[0]), scale(coords[1]), scale(brightness)}}
+}
+
+// Frame holds the colors sent to an entertainment group at once
+type Frame struct {
+	ColorSpace ColorSpace
+	Colors     []StreamColor
+}
+
+// encode builds a HueStream message holding the frame
+// Version 1 addresses lights by their ID. Version 2 addresses channels of the entertainment configuration
+// with the given ID.
+func (frame Frame) encode(version int, sequence uint8, configurationID string) ([]byte, error) {
+	message := []byte("HueStream")
+	message = append(message, byte(version), 0x00, sequence, 0x00, 0x00, byte(frame.ColorSpace), 0x00)
+
+	switch version {
+	case 1:
+		if len(frame.Colors) > maximumLightsPerFrame {
+			return nil, fmt.Errorf("Frame has %d lights but at most %d are allowed", len(frame.Colors), maximumLightsPerFrame)
+		}
+
+		for _, color := range frame.Colors {
+			message = append(message, 0x00) // Device type (always a light)
+			message = appendUint16(message, color.ID)
+			message = appendValues(message, color.Values)
+		}
+	case 2:
+		if len(configurationID) != entertainmentConfigurationIDLength {
+			return nil, fmt.Errorf("Invalid entertainment configuration ID `%s`", configurationID)
+		}
+
+		if len(frame.Colors) > maximumChannelsPerFrame {
+			return nil, fmt.Errorf("Frame has %d channels but at most %d are allowed", len(frame.Colors), maximumChannelsPerFrame)
+		}
+
+		message = append(message, configurationID...)
+		for _, color := range frame.Colors {
+			if color.ID > math.MaxUint8 {
+				return nil, fmt.Errorf("Invalid channel `%d`", color.ID)
+			}
+
+			message = append(message, byte(color.ID))
+			message = appendValues(message, color.Values)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported HueStream version `%d`", version)
+	}
+
+	return message, nil
+}
+
+// appendUint16 appends a big-endian uint16 to a message
+func appendUint16(message []byte, value uint16) []byte {
+	return append(message, byte(value>>8), byte(value))
+}
+
+func appendValues(message []byte, values [3]uint16) []byte {
+	for _, value := range values {
+		message = appendUint16(message, value)
+	}
+
+	return message
+}
+
+// StreamOptions configures an entertainment stream
+type StreamOptions struct {
+	// HueStream version (1 if zero). Version 2 requires ConfigurationID.
+	Version int
+
+	// The ID of the entertainment configuration to stream to (HueStream v2 only)
+	ConfigurationID string
+
+	// Frames per second (MaximumStreamRate if zero or greater)
+	Rate int
+
+	// UDP port the bridge accepts streams on (EntertainmentPort if zero)
+	Port int
+}
+
+// EntertainmentStream sends frames to an entertainment group over DTLS
+// An EntertainmentStream is safe for concurrent use.
+type EntertainmentStream struct {
+	group    *Group
+	conn     net.Conn
+	version  int
+	configID string
+	interval time.Duration
+
+	mu       sync.Mutex
+	last     time.Time
+	sequence uint8
+}
+
+// SetStreaming activates or deactivates streaming to an entertainment group
+func (group *Group) SetStreaming(ctx context.Context, active bool) error {
+	if group.Type != GroupTypeEntertainment {
+		return ErrNotEntertainmentGroup
+	}
+
+	payload := map[string]interface{}{
+		"stream": map[string]bool{"active": active},
+	}
+
+	_, err := group.Bridge.put(ctx, fmt.Sprintf("/groups/%s", group.ID), payload)
+	group.Bridge.Cache.Invalidate(ResourceGroups)
+	if err != nil {
+		return err
+	}
+
+	if group.Stream == nil {
+		group.Stream = &GroupStream{}
+	}
+	group.Stream.Active = active
+
+	return nil
+}
+
+// StartStreaming activates streaming to an entertainment group and opens a DTLS session with its bridge
+// The bridge must have a client key (see Connect). The stream should be closed when it's no longer
+// needed, which also deactivates streaming.
+func (group *Group) StartStreaming(ctx context.Context, options StreamOptions) (*EntertainmentStream, error) {
+	if group.Bridge.ClientKey == "" {
+		return nil, ErrNoClientKey
+	}
+
+	psk, err := hex.DecodeString(group.Bridge.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid client key: %w", err)
+	}
+
+	version := options.Version
+	if version == 0 {
+		version = 1
+	}
+
+	rate := options.Rate
+	if rate <= 0 || rate > MaximumStreamRate {
+		rate = MaximumStreamRate
+	}
+
+	port := options.Port
+	if port == 0 {
+		port = EntertainmentPort
+	}
+
+	err = group.SetStreaming(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &dtls.Config{
+		PSK: func([]byte) ([]byte, error) {
+			return psk, nil
+		},
+		PSKIdentityHint: []byte(group.Bridge.Username),
+		CipherSuites:    []dtls.CipherSuiteID{dtls.TLS_PSK_WITH_AES_128_GCM_SHA256},
+	}
+
+	conn, err := dtls.DialWithContext(ctx, "udp", &net.UDPAddr{IP: group.Bridge.IP, Port: port}, config)
+	if err != nil {
+		group.SetStreaming(context.Background(), false)
+		return nil, fmt.Errorf("Failed to open entertainment stream: %w", err)
+	}
+
+	return &EntertainmentStream{
+		group:    group,
+		conn:     conn,
+		version:  version,
+		configID: options.ConfigurationID,
+		interval: time.Second / time.Duration(rate),
+	}, nil
+}
+
+// Send sends a frame, waiting if the previous frame was sent too recently for the stream's rate
+func (stream *EntertainmentStream) Send(ctx context.Context, frame Frame) error {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	message, err := frame.encode(stream.version, stream.sequence, stream.configID)
+	if err != nil {
+		return err
+	}
+
+	if wait := stream.interval - time.Since(stream.last); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	_, err = stream.conn.Write(message)
+	if err != nil {
+		return err
+	}
+
+	stream.last = time.Now()
+	stream.sequence++
+
+	return nil
+}
+
+// Close closes the DTLS session and deactivates streaming to the group
+func (stream *EntertainmentStream) Close() error {
+	err := stream.conn.Close()
+
+	deactivateErr := stream.group.SetStreaming(context.Background(), false)
+	if err == nil {
+		err = deactivateErr
+	}
+
+	return err
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFrameEncode(t *testing.T) {
+	configID := "1a8d99cc-967b-44f2-9202-43f976c0fa6b"
+	header := func(version, colorSpace byte) []byte {
+		return append([]byte("HueStream"), version, 0x00, 0x07, 0x00, 0x00, colorSpace, 0x00)
+	}
+
+	tests := []struct {
+		name     string
+		version  int
+		frame    Frame
+		expected []byte
+		err      string
+	}{
+		{
+			name:    "Test v1 RGB frame",
+			version: 1,
+			frame: Frame{
+				ColorSpace: ColorSpaceRGB,
+				Colors:     []StreamColor{RGBColor(1, 0xff, 0x00, 0x80), RGBColor(258, 0x00, 0x01, 0x00)},
+			},
+			expected: append(header(0x01, 0x00),
+				0x00, 0x00, 0x01, 0xff, 0xff, 0x00, 0x00, 0x80, 0x80,
+				0x00, 0x01, 0x02, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00,
+			),
+		},
+		{
+			name:    "Test v2 XY frame",
+			version: 2,
+			frame: Frame{
+				ColorSpace: ColorSpaceXY,
+				Colors:     []StreamColor{XYColor(3, CIECoord{0.5, 1.5}, 1)},
+			},
+			expected: append(append(header(0x02, 0x01), configID...),
+				0x03, 0x80, 0x00, 0xff, 0xff, 0xff, 0xff,
+			),
+		},
+		{
+			name:    "Test too many lights",
+			version: 1,
+			frame:   Frame{Colors: make([]StreamColor, maximumLightsPerFrame+1)},
+			err:     "at most 10 are allowed",
+		},
+		{
+			name:    "Test invalid channel",
+			version: 2,
+			frame:   Frame{Colors: []StreamColor{{ID: 256}}},
+			err:     "Invalid channel",
+		},
+		{
+			name:    "Test unsupported version",
+			version: 3,
+			err:     "Unsupported HueStream version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := tt.frame.encode(tt.version, 0x07, configID)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected error containing `%s` but got %v", tt.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, message); diff != "" {
+				t.Errorf("Message mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
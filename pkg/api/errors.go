@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Error types reported by the bridge in APIError.Type
+const (
+	ErrorTypeUnauthorized           = 1
+	ErrorTypeInvalidJSON            = 2
+	ErrorTypeResourceNotAvailable   = 3
+	ErrorTypeMethodNotAvailable     = 4
+	ErrorTypeMissingParameters      = 5
+	ErrorTypeParameterNotAvailable  = 6
+	ErrorTypeInvalidValue           = 7
+	ErrorTypeParameterNotModifiable = 8
+	ErrorTypeLinkButtonNotPressed   = 101
+	ErrorTypeDeviceOff              = 201
+)
+
+// Errors that an APIError can be compared to with errors.Is
+var (
+	ErrUnauthorized          = errors.New("Unauthorized user")
+	ErrResourceNotAvailable  = errors.New("Resource not available")
+	ErrLinkButtonNotPressed  = errors.New("Link button not pressed")
+	ErrParameterNotAvailable = errors.New("Parameter not available")
+	ErrDeviceOff             = errors.New("Device is off")
+)
+
+var apiErrorTypes = map[error]int{
+	ErrUnauthorized:          ErrorTypeUnauthorized,
+	ErrResourceNotAvailable:  ErrorTypeResourceNotAvailable,
+	ErrLinkButtonNotPressed:  ErrorTypeLinkButtonNotPressed,
+	ErrParameterNotAvailable: ErrorTypeParameterNotAvailable,
+	ErrDeviceOff:             ErrorTypeDeviceOff,
+}
+
+// Is allows an APIError to be compared to the sentinel errors above with errors.Is
+func (err APIError) Is(target error) bool {
+	errorType, ok := apiErrorTypes[target]
+	return ok && err.Type == errorType
+}
+
+// The number of bytes of a response body kept in a ResponseError
+const excerptLength = 200
+
+// ResponseError is returned when the bridge's response can't be used
+// This happens when the bridge responds with an unsuccessful status, with something other than JSON,
+// or with an error array instead of the expected resource.
+type ResponseError struct {
+	StatusCode  int
+	ContentType string
+	Excerpt     string // The beginning of the response body
+	Err         error  // The underlying error (an APIError for error arrays), if any
+}
+
+func (err *ResponseError) Error() string {
+	var reason string
+	switch {
+	case err.StatusCode < 200 || err.StatusCode > 299:
+		reason = fmt.Sprintf("Bridge responded with status %d %s", err.StatusCode, http.StatusText(err.StatusCode))
+	case err.Err != nil:
+		reason = err.Err.Error()
+	default:
+		reason = fmt.Sprintf("Bridge responded with unexpected content type `%s`", err.ContentType)
+	}
+
+	return fmt.Sprintf("%s (body: `%s`)", reason, err.Excerpt)
+}
+
+// Unwrap allows the underlying error to be inspected with errors.Is and errors.As
+func (err *ResponseError) Unwrap() error {
+	return err.Err
+}
+
+func newResponseError(resp *http.Response, body []byte, err error) *ResponseError {
+	excerpt := bytes.TrimSpace(body)
+	if len(excerpt) > excerptLength {
+		excerpt = append(excerpt[:excerptLength:excerptLength], "..."...)
+	}
+
+	return &ResponseError{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Excerpt:     string(excerpt),
+		Err:         err,
+	}
+}
+
+// isJSONContentType returns true if a content type could hold JSON
+// Some bridges (and third party emulators) don't set a content type so a missing one is accepted.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasSuffix(mediaType, "json") || mediaType == "text/plain"
+}
+
+// readResponse reads a response's body after checking its status and content type
+func readResponse(resp *http.Response) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || !isJSONContentType(resp.Header.Get("Content-Type")) {
+		return nil, newResponseError(resp, body, nil)
+	}
+
+	return body, nil
+}
+
+// decodeResource decodes a response expected to hold a JSON object into v
+// The bridge responds with an error array (rather than the resource) for unauthorized users, missing
+// resources, etc. The first error in the array is returned wrapped in a ResponseError.
+func decodeResource(resp *http.Response, v interface{}) error {
+	body, err := readResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		items := []updateResponse{}
+		if json.Unmarshal(trimmed, &items) == nil {
+			for _, item := range items {
+				if item.Error != nil {
+					return newResponseError(resp, body, *item.Error)
+				}
+			}
+		}
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return newResponseError(resp, body, err)
+	}
+
+	return nil
+}
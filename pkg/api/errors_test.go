@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		contentType     string
+		body            string
+		expectedStatus  int
+		expectedExcerpt string
+		expectedErr     error
+	}{
+		{
+			name:            "Test unsuccessful status",
+			status:          http.StatusServiceUnavailable,
+			contentType:     "text/html",
+			body:            "<html><body>Bridge is busy</body></html>",
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedExcerpt: "<html><body>Bridge is busy</body></html>",
+		},
+		{
+			name:            "Test unexpected content type",
+			status:          http.StatusOK,
+			contentType:     "text/html; charset=utf-8",
+			body:            "<html></html>",
+			expectedStatus:  http.StatusOK,
+			expectedExcerpt: "<html></html>",
+		},
+		{
+			name:            "Test unauthorized user",
+			status:          http.StatusOK,
+			contentType:     "application/json",
+			body:            `[{"error":{"type":1,"address":"/lights","description":"unauthorized user"}}]`,
+			expectedStatus:  http.StatusOK,
+			expectedExcerpt: `[{"error":{"type":1,"address":"/lights","description":"unauthorized user"}}]`,
+			expectedErr:     ErrUnauthorized,
+		},
+		{
+			name:            "Test invalid JSON",
+			status:          http.StatusOK,
+			body:            `{"1": `,
+			expectedStatus:  http.StatusOK,
+			expectedExcerpt: `{"1":`,
+		},
+		{
+			name:            "Test long body is truncated",
+			status:          http.StatusInternalServerError,
+			body:            strings.Repeat("a", 500),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedExcerpt: strings.Repeat("a", excerptLength) + "...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := NewTestAPI(func(*http.Request) (*http.Response, error) {
+				header := http.Header{}
+				if tt.contentType != "" {
+					header.Set("Content-Type", tt.contentType)
+				}
+
+				return &http.Response{
+					StatusCode: tt.status,
+					Header:     header,
+					Body:       ioutil.NopCloser(bytes.NewReader([]byte(tt.body))),
+				}, nil
+			}, DefaultBrowse)
+
+			bridge := Bridge{IP: []byte{127, 0, 0, 1}, API: api}
+
+			for name, get := range map[string]func() error{
+				"lights": func() error { _, err := bridge.GetLights(); return err },
+				"groups": func() error { _, err := bridge.GetGroups(); return err },
+				"scenes": func() error { _, err := bridge.GetScenes(); return err },
+			} {
+				err := get()
+
+				var respErr *ResponseError
+				if !errors.As(err, &respErr) {
+					t.Fatalf("Expected a response error for %s but got %v", name, err)
+				}
+
+				if respErr.StatusCode != tt.expectedStatus {
+					t.Errorf("Expected status %d for %s but got %d", tt.expectedStatus, name, respErr.StatusCode)
+				}
+
+				if respErr.Excerpt != tt.expectedExcerpt {
+					t.Errorf("Expected excerpt %q for %s but got %q", tt.expectedExcerpt, name, respErr.Excerpt)
+				}
+
+				if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Expected %v for %s but got %v", tt.expectedErr, name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	err := APIError{Type: ErrorTypeLinkButtonNotPressed, Description: "link button not pressed"}
+
+	if !errors.Is(err, ErrLinkButtonNotPressed) {
+		t.Errorf("Expected %v to be %v", err, ErrLinkButtonNotPressed)
+	}
+
+	if errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected %v not to be %v", err, ErrUnauthorized)
+	}
+}
+
+func TestConnectLinkButtonNotPressed(t *testing.T) {
+	api := NewTestAPI(func(*http.Request) (*http.Response, error) {
+		json := `[{"error": {"type": 101, "address": "", "description": "link button not pressed"}}]`
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(json))),
+		}, nil
+	}, DefaultBrowse)
+
+	bridge := Bridge{IP: []byte{127, 0, 0, 1}, API: api}
+
+	_, err := bridge.Connect()
+	if !errors.Is(err, ErrLinkButtonNotPressed) {
+		t.Errorf("Expected %v but got %v", ErrLinkButtonNotPressed, err)
+	}
+}
@@ -0,0 +1,137 @@
+package api_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+)
+
+func TestFakeBridge(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	bridges, err := server.API().Discover()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if len(bridges) != 1 {
+		t.Fatalf("Expected 1 bridge but got %d", len(bridges))
+	}
+	bridge := bridges[0]
+
+	t.Run("Test connecting", func(t *testing.T) {
+		_, err := bridge.Connect()
+		if !errors.Is(err, api.ErrLinkButtonNotPressed) {
+			t.Errorf("Expected %v but got %v", api.ErrLinkButtonNotPressed, err)
+		}
+
+		server.Bridge.PressLinkButton()
+
+		username, err := bridge.Connect()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if username == "" || bridge.Username != username {
+			t.Errorf("Expected username to be set but got `%s`", bridge.Username)
+		}
+	})
+
+	getLights := func(t *testing.T) map[string]api.Light {
+		t.Helper()
+
+		lights, err := bridge.GetLights()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		byID := map[string]api.Light{}
+		for _, light := range lights {
+			byID[light.ID] = light
+		}
+
+		return byID
+	}
+
+	t.Run("Test controlling lights", func(t *testing.T) {
+		light := getLights(t)["1"]
+
+		err := light.ToggleLight()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		err = light.SetColorTemperatureKelvin(4000)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if fetched := getLights(t)["1"]; !fetched.State.On || fetched.State.Temperature != 250 {
+			t.Errorf("Expected light to be on at 250 mireds but got %+v", fetched.State)
+		}
+	})
+
+	t.Run("Test light is off", func(t *testing.T) {
+		light := getLights(t)["3"]
+
+		err := light.SetBrightness(10)
+		if !errors.Is(err, api.ErrDeviceOff) {
+			t.Errorf("Expected %v but got %v", api.ErrDeviceOff, err)
+		}
+	})
+
+	t.Run("Test groups and scenes", func(t *testing.T) {
+		groups, err := bridge.GetGroups()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+		if len(groups) != 2 || !groups[0].State.AnyOn || groups[0].State.AllOn {
+			t.Fatalf("Expected the kitchen to be partially on but got %+v", groups)
+		}
+
+		err = groups[0].RecallScene("relax")
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if light := getLights(t)["2"]; !light.State.On || light.State.Brightness != 1 {
+			t.Errorf("Expected scene to be recalled but got %+v", light.State)
+		}
+
+		scenes, err := bridge.GetScenes()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if len(scenes) != 1 || scenes[0].Name != "Relax" {
+			t.Errorf("Expected the relax scene but got %+v", scenes)
+		}
+	})
+
+	t.Run("Test sensors", func(t *testing.T) {
+		sensors, err := bridge.GetSensors()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if len(sensors) != 1 || sensors[0].State["daylight"] != true {
+			t.Errorf("Expected a daylight sensor but got %+v", sensors)
+		}
+	})
+
+	t.Run("Test unauthorized user", func(t *testing.T) {
+		stranger := bridge
+		stranger.Username = "stranger"
+
+		_, err := stranger.GetLights()
+		if !errors.Is(err, api.ErrUnauthorized) {
+			t.Errorf("Expected %v but got %v", api.ErrUnauthorized, err)
+		}
+	})
+}
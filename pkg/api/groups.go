@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// Group types reported by the bridge
+const (
+	GroupTypeLightGroup    = "LightGroup"
+	GroupTypeRoom          = "Room"
+	GroupTypeZone          = "Zone"
+	GroupTypeEntertainment = "Entertainment"
+)
+
+// GroupState represents the aggregate on state of a group's lights
+type GroupState struct {
+	AllOn bool `json:"all_on"`
+	AnyOn bool `json:"any_on"`
+}
+
+// Group represents a group of lights (a room, zone, entertainment area, etc.)
+type Group struct {
+	Name    string     `json:"name"`
+	Lights  []string   `json:"lights"`
+	Sensors []string   `json:"sensors"`
+	Type    string     `json:"type"`
+	State   GroupState `json:"state"`
+	Recycle bool       `json:"recycle"`
+	Class   string     `json:"class"`
+
+	// The last state sent to the group. This is not necessarily the state of every light in the group.
+	Action LightState `json:"action"`
+
+	// Only set for entertainment groups
+	Stream *GroupStream `json:"stream,omitempty"`
+
+	ID     string  `json:"-"`
+	Bridge *Bridge `json:"-"`
+}
+
+// GetGroups retrieves all the groups on a certain bridge
+func (bridge *Bridge) GetGroups() ([]Group, error) {
+	data, ok := bridge.Cache.cachedGroups()
+	if !ok {
+		err := bridge.get(context.Background(), "/groups", &data)
+		if err != nil {
+			return nil, err
+		}
+
+		bridge.Cache.storeGroups(data)
+	}
+
+	groups := []Group{}
+
+	for id, group := range data {
+		group.ID = id
+		group.Bridge = bridge
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// SetState validates a state update and sends it to every light in the group
+// The group's lights may have different capabilities so the update is only checked against the range
+// of standard Hue bulbs. The group's action and state are updated if the bridge accepts the update and
+// then reconciled with the values reported by the bridge.
+func (group *Group) SetState(update LightStateUpdate) error {
+	return group.SetStateContext(context.Background(), update)
+}
+
+// SetStateContext is like SetState but sends the request with a context (see WithPriority)
+func (group *Group) SetStateContext(ctx context.Context, update LightStateUpdate) error {
+	err := update.Validate(nil)
+	if err != nil {
+		return err
+	}
+
+	// Repeating a relative change would apply it twice
+	if update.hasDeltas() {
+		ctx = withNonIdempotent(ctx)
+	}
+
+	updated, err := group.Bridge.put(ctx, fmt.Sprintf("/groups/%s/action", group.ID), update)
+	// Every light in the group may have changed
+	group.Bridge.Cache.Invalidate(ResourceLights)
+
+	if err != nil {
+		group.Action.reconcile(updated)
+		group.Bridge.Cache.Invalidate(ResourceGroups)
+		return err
+	}
+
+	group.Action.apply(update, DefaultTemperatureRange)
+	if update.On != nil {
+		group.State.AllOn = *update.On
+		group.State.AnyOn = *update.On
+	}
+	err = group.Action.reconcile(updated)
+	group.Bridge.Cache.storeGroupState(group.ID, group.State, group.Action)
+
+	return err
+}
+
+// setState builds an update and sends it to every light in the group
+func (group *Group) setState(builder *StateBuilder) error {
+	update, err := builder.Build(nil)
+	if err != nil {
+		return err
+	}
+
+	return group.SetState(update)
+}
+
+// SetOn turns every light in a group on or off
+func (group *Group) SetOn(on bool, options ...StateOption) error {
+	return group.setState(NewStateBuilder().On(on).With(options...))
+}
+
+// SetBrightness sets the brightness of every light in a group
+func (group *Group) SetBrightness(brightness uint8, options ...StateOption) error {
+	return group.setState(NewStateBuilder().Brightness(int(brightness)).With(options...))
+}
+
+// ToggleGroup turns every light in a group off if any are on or on if they are all off
+func (group *Group) ToggleGroup(options ...StateOption) error {
+	return group.SetOn(!group.State.AnyOn, options...)
+}
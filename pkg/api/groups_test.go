@@ -0,0 +1,105 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetGroups(t *testing.T) {
+	api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		json := `{
+			"1": {
+				"name": "Kitchen",
+				"lights": ["1", "2"],
+				"sensors": [],
+				"type": "Room",
+				"state": {"all_on": false, "any_on": true},
+				"recycle": false,
+				"class": "Kitchen",
+				"action": {"on": true, "bri": 254, "alert": "none", "colormode": "ct", "ct": 366}
+			}
+		}`
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(json)),
+		}, nil
+	}, DefaultBrowse)
+
+	bridge := Bridge{
+		IP:  []byte{127, 0, 0, 1},
+		API: api,
+	}
+
+	groups, err := bridge.GetGroups()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	expected := []Group{
+		{
+			Name:    "Kitchen",
+			Lights:  []string{"1", "2"},
+			Sensors: []string{},
+			Type:    GroupTypeRoom,
+			State:   GroupState{AllOn: false, AnyOn: true},
+			Class:   "Kitchen",
+			Action:  LightState{On: true, Brightness: 254, Alert: "none", ColorMode: "ct", Temperature: 366},
+			ID:      "1",
+			Bridge:  &bridge,
+		},
+	}
+
+	if diff := cmp.Diff(expected, groups); diff != "" {
+		t.Errorf("Groups mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGroupSetState(t *testing.T) {
+	var path, body string
+	api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		path = req.URL.Path
+		body = string(data)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"success":{"/groups/1/action/bri":150}}]`)),
+		}, nil
+	}, DefaultBrowse)
+
+	group := Group{
+		ID:     "1",
+		Action: LightState{Brightness: 100},
+		Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, Username: "user", API: api},
+	}
+
+	update, err := NewStateBuilder().BrightnessDelta(50).Build(nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	err = group.SetState(update)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if path != "/api/user/groups/1/action" {
+		t.Errorf("Expected request to group action but got %s", path)
+	}
+
+	if !jsonEqual(t, body, `{"bri_inc":50}`) {
+		t.Errorf("Unexpected body %s", body)
+	}
+
+	if group.Action.Brightness != 150 {
+		t.Errorf("Expected brightness to be reconciled to 150 but got %d", group.Action.Brightness)
+	}
+}
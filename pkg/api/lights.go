@@ -1,15 +1,11 @@
 package api
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"time"
 )
 
-// TODO: How to enforce valid values?
-
 // CIECoord represents coordinates in the CIE color space.
 // The first entry is the x-coordinate and the second entry is the y-coordinate.
 // Both values must be between 0 and 1.
@@ -28,15 +24,6 @@ type LightState struct {
 	Mode        string   `json:"mode"`
 	ColorMode   string   `json:"colormode"`
 	Reachable   bool     `json:"reachable"`
-	//TransitionTime uint16   `json:"transitiontime"` // Values are multiples of 100ms and default is 4 (400ms)
-
-	// Setting a delta value to 0 stops any ongoing transition
-
-	//BrightnessDelta  int16    `json:"bri_inc"` // Valid values are -254 to 254.
-	//SaturationDelta  int16    `json:"sat_inc"` // Valid values are -254 to 254.
-	//HueDelta         int16    `json:"hue_inc"` // Valid values are -65534 to 65334.
-	//TemperatureDelta int16    `json:"ct_inc"`  // Valid values are -65534 to 65534.
-	//CIEDelta         CIECoord `json:"xy_incl"` // TODO: Undertand this better
 }
 
 type hueTime struct {
@@ -56,6 +43,15 @@ func (ht *hueTime) UnmarshalJSON(data []byte) error {
 	return err
 }
 
+// MarshalJSON formats times the way the bridge does so they can be read back by UnmarshalJSON
+func (ht hueTime) MarshalJSON() ([]byte, error) {
+	if ht.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return []byte(ht.Format("\"2006-01-02T15:04:05\"")), nil
+}
+
 // LightSWUpdate holds information about a light's updatability
 // TODO: better doc
 type LightSWUpdate struct {
@@ -125,17 +121,14 @@ type Light struct {
 
 // GetLights retrieves all the lights on a certain bridge
 func (bridge *Bridge) GetLights() ([]Light, error) {
-	url := fmt.Sprintf("http://%s/api/%s/lights", bridge.IP, bridge.Username)
-	resp, err := bridge.API.Client.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var data map[string]Light
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	if err != nil {
-		return nil, err
+	data, ok := bridge.Cache.cachedLights()
+	if !ok {
+		err := bridge.get(context.Background(), "/lights", &data)
+		if err != nil {
+			return nil, err
+		}
+
+		bridge.Cache.storeLights(data)
 	}
 
 	lights := []Light{}
@@ -151,28 +144,84 @@ func (bridge *Bridge) GetLights() ([]Light, error) {
 
 // ToggleLight turns a lit light off and an unlit light on
 // The state sent to the bridge depends on the light object's `On` attribute.
-func (light *Light) ToggleLight() error {
-	url := fmt.Sprintf("http://%s/api/%s/lights/%s/state", light.Bridge.IP, light.Bridge.Username, light.ID)
+func (light *Light) ToggleLight(options ...StateOption) error {
+	return light.SetOn(!light.State.On, options...)
+}
+
+// SetState validates a state update and sends it to the bridge
+// An error is returned without contacting the bridge if the update is invalid or requires features the
+// light does not support. The light's state is updated if the bridge accepts the update and then reconciled
+// with the values reported by the bridge (which matters for relative changes).
+func (light *Light) SetState(update LightStateUpdate) error {
+	return light.SetStateContext(context.Background(), update)
+}
+
+// SetStateContext is like SetState but sends the request with a context (see WithPriority)
+func (light *Light) SetStateContext(ctx context.Context, update LightStateUpdate) error {
+	err := light.require(update.requiredFeatures())
+	if err != nil {
+		return err
+	}
 
-	newOnState := !light.State.On
-	payload, err := json.Marshal(map[string]bool{"on": newOnState})
+	err = update.Validate(light)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(payload))
+	// Repeating a relative change would apply it twice
+	if update.hasDeltas() {
+		ctx = withNonIdempotent(ctx)
+	}
+
+	updated, err := light.Bridge.put(ctx, fmt.Sprintf("/lights/%s/state", light.ID), update)
+	// The state of every group with the light may have changed
+	light.Bridge.Cache.Invalidate(ResourceGroups)
+
 	if err != nil {
+		// Keep whatever the bridge reported as successfully updated
+		light.State.reconcile(updated)
+		light.Bridge.Cache.Invalidate(ResourceLights)
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := light.Bridge.API.Client.Do(req)
+	light.State.apply(update, light.TemperatureRange())
+	err = light.State.reconcile(updated)
+	light.Bridge.Cache.storeLightState(light.ID, light.State)
+
+	return err
+}
+
+// setState builds an update and sends it to the bridge
+func (light *Light) setState(builder *StateBuilder) error {
+	update, err := builder.Build(light)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	light.State.On = newOnState
+	return light.SetState(update)
+}
+
+// SetOn turns a light on or off
+func (light *Light) SetOn(on bool, options ...StateOption) error {
+	return light.setState(NewStateBuilder().On(on).With(options...))
+}
+
+// SetBrightness sets a light's brightness
+func (light *Light) SetBrightness(brightness uint8, options ...StateOption) error {
+	return light.setState(NewStateBuilder().Brightness(int(brightness)).With(options...))
+}
+
+// SetHue sets a light's hue
+func (light *Light) SetHue(hue uint16, options ...StateOption) error {
+	return light.setState(NewStateBuilder().Hue(int(hue)).With(options...))
+}
+
+// SetSaturation sets a light's saturation
+func (light *Light) SetSaturation(saturation uint8, options ...StateOption) error {
+	return light.setState(NewStateBuilder().Saturation(int(saturation)).With(options...))
+}
 
-	return nil
+// SetCIECoords sets a light's color using coordinates in the CIE color space
+func (light *Light) SetCIECoords(coords CIECoord, options ...StateOption) error {
+	return light.setState(NewStateBuilder().CIECoords(coords[0], coords[1]).With(options...))
 }
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority determines the order in which requests waiting on the rate limiter are sent
+type Priority int
+
+// Request priorities. Requests with a higher priority are always sent before waiting requests with a
+// lower priority.
+const (
+	PriorityLow    Priority = iota // Bulk changes (scripts, effects, schedules)
+	PriorityNormal                 // The default
+	PriorityHigh                   // Interactive changes (toggling a light in a UI)
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context that sends requests made with it at the given priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// priorityFromContext returns the priority stored in a context or PriorityNormal if there isn't one
+func priorityFromContext(ctx context.Context) Priority {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok || priority < PriorityLow || priority > PriorityHigh {
+		return PriorityNormal
+	}
+
+	return priority
+}
+
+type reservedKey struct{}
+
+// withReservation returns a context for a request that has already waited on the rate limiter
+func withReservation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reservedKey{}, true)
+}
+
+// rateLimitReserved returns true if a request made with the context has already waited on the rate limiter
+func rateLimitReserved(ctx context.Context) bool {
+	reserved, _ := ctx.Value(reservedKey{}).(bool)
+	return reserved
+}
+
+// RequestClass determines which budget a request is counted against
+type RequestClass int
+
+// Request classes. The bridge handles far fewer group commands than light commands.
+const (
+	ClassLight RequestClass = iota // Light commands and everything else that isn't a group command
+	ClassGroup                     // Commands sent to a group (including scene recalls)
+)
+
+// classifyRequest returns the budget a request should be counted against
+func classifyRequest(req *http.Request) RequestClass {
+	if req.Method != http.MethodGet && strings.Contains(req.URL.Path, "/groups/") {
+		return ClassGroup
+	}
+
+	return ClassLight
+}
+
+// RateLimit describes a token bucket
+type RateLimit struct {
+	// The number of requests allowed per second (requests are not limited if this isn't positive)
+	PerSecond float64
+
+	// The number of requests that can be sent at once after a quiet period
+	Burst int
+}
+
+// Default rate limits recommended for Hue bridges
+var (
+	DefaultLightRateLimit = RateLimit{PerSecond: 10, Burst: 1}
+	DefaultGroupRateLimit = RateLimit{PerSecond: 1, Burst: 1}
+)
+
+// RateLimiter schedules requests to a bridge so it isn't flooded with commands
+// Light and group commands have separate budgets. Requests waiting for a budget are queued by priority
+// (and in order of arrival within a priority). A RateLimiter is safe for concurrent use and should be
+// shared by everything talking to the same bridge.
+type RateLimiter struct {
+	buckets map[RequestClass]*bucket
+}
+
+// NewRateLimiter creates a rate limiter with the given light and group budgets
+func NewRateLimiter(light, group RateLimit) *RateLimiter {
+	return &RateLimiter{
+		buckets: map[RequestClass]*bucket{
+			ClassLight: newBucket(light),
+			ClassGroup: newBucket(group),
+		},
+	}
+}
+
+// NewDefaultRateLimiter creates a rate limiter with the rate limits recommended for Hue bridges
+func NewDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(DefaultLightRateLimit, DefaultGroupRateLimit)
+}
+
+// Wait blocks until a request of the given class and priority may be sent
+// An error is returned if the context is done first.
+func (limiter *RateLimiter) Wait(ctx context.Context, class RequestClass, priority Priority) error {
+	b, ok := limiter.buckets[class]
+	if !ok {
+		b = limiter.buckets[ClassLight]
+	}
+
+	return b.wait(ctx, priority)
+}
+
+// waiter represents a request queued on a bucket
+type waiter struct {
+	ready chan struct{}
+}
+
+// bucket is a token bucket with a queue of waiting requests for each priority
+type bucket struct {
+	mu          sync.Mutex
+	limit       RateLimit
+	tokens      float64
+	lastRefill  time.Time
+	queues      [numPriorities][]*waiter
+	dispatching bool
+}
+
+func newBucket(limit RateLimit) *bucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return &bucket{
+		limit:      limit,
+		tokens:     float64(limit.Burst),
+		lastRefill: time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last refill (must be called with the lock held)
+func (b *bucket) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.lastRefill).Seconds() * b.limit.PerSecond
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+	b.lastRefill = now
+}
+
+// next returns the first waiter in the highest priority queue (must be called with the lock held)
+func (b *bucket) next() (*waiter, Priority) {
+	for priority := numPriorities - 1; priority >= 0; priority-- {
+		if len(b.queues[priority]) > 0 {
+			return b.queues[priority][0], Priority(priority)
+		}
+	}
+
+	return nil, PriorityNormal
+}
+
+func (b *bucket) wait(ctx context.Context, priority Priority) error {
+	if b.limit.PerSecond <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.refill()
+
+	// Skip the queue if nobody else is waiting
+	if queued, _ := b.next(); queued == nil && b.tokens >= 1 {
+		b.tokens--
+		b.mu.Unlock()
+		return nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	b.queues[priority] = append(b.queues[priority], w)
+	if !b.dispatching {
+		b.dispatching = true
+		go b.dispatch()
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			// The request was scheduled while the context was finishing
+			return nil
+		default:
+		}
+
+		b.remove(w, priority)
+		return ctx.Err()
+	}
+}
+
+// remove takes a waiter out of its queue (must be called with the lock held)
+func (b *bucket) remove(w *waiter, priority Priority) {
+	queue := b.queues[priority]
+	for i, candidate := range queue {
+		if candidate == w {
+			b.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch hands out tokens to waiting requests until there are no more waiting requests
+func (b *bucket) dispatch() {
+	for {
+		b.mu.Lock()
+		b.refill()
+
+		w, priority := b.next()
+		if w == nil {
+			b.dispatching = false
+			b.mu.Unlock()
+			return
+		}
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.queues[priority] = b.queues[priority][1:]
+			close(w.ready)
+			b.mu.Unlock()
+			continue
+		}
+
+		delay := time.Duration((1 - b.tokens) / b.limit.PerSecond * float64(time.Second))
+		b.mu.Unlock()
+
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterThrottles(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{PerSecond: 20, Burst: 1}, DefaultGroupRateLimit)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		err := limiter.Wait(context.Background(), ClassLight, PriorityNormal)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+
+	// The first request uses the burst and the next two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected requests to be throttled but they took %v", elapsed)
+	}
+}
+
+func TestRateLimiterSeparatesBudgets(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{PerSecond: 10, Burst: 1}, RateLimit{PerSecond: 0.1, Burst: 1})
+
+	err := limiter.Wait(context.Background(), ClassGroup, PriorityNormal)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	start := time.Now()
+	err = limiter.Wait(context.Background(), ClassLight, PriorityNormal)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected light request not to wait on group budget but it took %v", elapsed)
+	}
+}
+
+func TestRateLimiterPriority(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{PerSecond: 10, Burst: 1}, DefaultGroupRateLimit)
+
+	// Use up the burst so the following requests have to queue
+	err := limiter.Wait(context.Background(), ClassLight, PriorityNormal)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+
+	enqueue := func(priority Priority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := limiter.Wait(context.Background(), ClassLight, priority)
+			if err != nil {
+				t.Errorf("Expected no error but got %v", err)
+			}
+
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+		}()
+
+		// Give the request time to be queued
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	enqueue(PriorityLow)
+	enqueue(PriorityLow)
+	enqueue(PriorityHigh)
+	wg.Wait()
+
+	expected := []Priority{PriorityHigh, PriorityLow, PriorityLow}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected requests to be sent in order %v but got %v", expected, order)
+		}
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{PerSecond: 0.1, Burst: 1}, DefaultGroupRateLimit)
+
+	err := limiter.Wait(context.Background(), ClassLight, PriorityNormal)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = limiter.Wait(ctx, ClassLight, PriorityNormal)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected %v but got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestClassifyRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		expected RequestClass
+	}{
+		{name: "Test light command", method: http.MethodPut, url: "http://bridge/api/user/lights/1/state", expected: ClassLight},
+		{name: "Test group command", method: http.MethodPut, url: "http://bridge/api/user/groups/1/action", expected: ClassGroup},
+		{name: "Test reading groups", method: http.MethodGet, url: "http://bridge/api/user/groups/1", expected: ClassLight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := classifyRequest(req); got != tt.expected {
+				t.Errorf("Expected class %d but got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAPIUsesRateLimiter(t *testing.T) {
+	api := NewTestAPI(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}, DefaultBrowse)
+	api.RateLimiter = NewRateLimiter(RateLimit{PerSecond: 20, Burst: 1}, DefaultGroupRateLimit)
+
+	light := Light{
+		Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		err := light.ToggleLight()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected requests to be throttled but they took %v", elapsed)
+	}
+}
@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/replay"
+)
+
+func TestThirdPartyLights(t *testing.T) {
+	fixture, err := replay.Load("testdata/third_party_lights.json")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	bridge := api.Bridge{
+		API:      &api.API{Client: http.Client{Transport: replay.NewReplayer(fixture)}},
+		IP:       []byte{127, 0, 0, 1},
+		Username: "user",
+	}
+
+	lights, err := bridge.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	sort.Slice(lights, func(i, j int) bool { return lights[i].ID < lights[j].ID })
+
+	tests := []struct {
+		name     string
+		features api.Feature
+		tempMin  uint
+		tempMax  uint
+	}{
+		{
+			name:     "Test IKEA color temperature bulb",
+			features: api.FeatureOnOff | api.FeatureBrightness | api.FeatureColorTemperature,
+			tempMin:  250,
+			tempMax:  454,
+		},
+		{
+			name:     "Test innr plug",
+			features: api.FeatureOnOff,
+		},
+	}
+
+	if len(lights) != len(tests) {
+		t.Fatalf("Expected %d lights but got %d", len(tests), len(lights))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			light := lights[i]
+
+			if light.Features() != tt.features {
+				t.Errorf("Expected features %v but got %v", tt.features, light.Features())
+			}
+
+			tempRange := light.Capabilities.Control.TemperatureRange
+			if tempRange.Minimum != tt.tempMin || tempRange.Maximum != tt.tempMax {
+				t.Errorf("Expected temperature range %d-%d but got %d-%d", tt.tempMin, tt.tempMax, tempRange.Minimum, tempRange.Maximum)
+			}
+		})
+	}
+}
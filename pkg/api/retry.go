@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how requests that fail for transient reasons are retried
+// Idempotent requests (GETs and PUTs of absolute values) are retried after timeouts, dropped connections
+// and 502/503/504 responses. Other requests (POSTs and relative changes) are only retried when the
+// connection to the bridge could not be established since the bridge can't have acted on them.
+type RetryPolicy struct {
+	// The maximum number of times a request is sent (including the first attempt)
+	MaxAttempts int
+
+	// The delay before the first retry. The delay doubles after each retry.
+	BaseDelay time.Duration
+
+	// The maximum delay between retries
+	MaxDelay time.Duration
+
+	// The fraction (0 to 1) of the delay that is randomized
+	Jitter float64
+
+	// Called before waiting to retry a request (optional)
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that is about to be retried
+type RetryEvent struct {
+	Request *http.Request
+	Attempt int           // The attempt that failed (starting at 1)
+	Err     error         // The error returned by the attempt (nil if the bridge responded)
+	Status  int           // The status code the bridge responded with (0 if there was an error)
+	Delay   time.Duration // How long until the next attempt
+}
+
+// DefaultRetryPolicy is a retry policy suitable for busy bridges on a local network
+// Copy it rather than pointing an API at it so changes to one API's policy don't leak into others.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Jitter:      0.2,
+}
+
+type nonIdempotentKey struct{}
+
+// withNonIdempotent returns a context for a request that must not be repeated if it may have reached the bridge
+func withNonIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nonIdempotentKey{}, true)
+}
+
+// isIdempotent returns true if sending a request more than once has the same effect as sending it once
+func isIdempotent(req *http.Request) bool {
+	if nonIdempotent, _ := req.Context().Value(nonIdempotentKey{}).(bool); nonIdempotent {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isConnectError returns true if an error occurred before a request could be sent
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isTransientError returns true if an error is likely to go away if the request is sent again
+// Timeouts of the HTTP client are transient. Requests whose own context is done are never retried (see send).
+func isTransientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return isConnectError(err) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTransientStatus returns true if a response status indicates the bridge was temporarily unable to respond
+func isTransientStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// shouldRetry decides whether an attempt should be retried
+func (policy *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if !isIdempotent(req) {
+		return err != nil && isConnectError(err)
+	}
+
+	if err != nil {
+		return isTransientError(err)
+	}
+
+	return isTransientStatus(resp.StatusCode)
+}
+
+// delay returns how long to wait after a failed attempt (starting at 1)
+func (policy *RetryPolicy) delay(attempt int) time.Duration {
+	delay := float64(policy.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if policy.MaxDelay > 0 {
+		delay = math.Min(delay, float64(policy.MaxDelay))
+	}
+
+	jitter := clampFloat(policy.Jitter, 0, 1)
+	delay += delay * jitter * (2*rand.Float64() - 1)
+
+	return time.Duration(delay)
+}
+
+// send sends a request, retrying it according to the policy
+// Every attempt is passed to sendOnce which is responsible for rate limiting.
+func (policy *RetryPolicy) send(req *http.Request, sendOnce func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx := req.Context()
+
+	// Only the first attempt can use a reservation made with the rate limiter
+	retryCtx := context.WithValue(ctx, reservedKey{}, false)
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(retryCtx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := sendOnce(attemptReq)
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.shouldRetry(attemptReq, resp, err) {
+			return resp, err
+		}
+
+		// Bodies can't be replayed without GetBody
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		event := RetryEvent{
+			Request: req,
+			Attempt: attempt,
+			Err:     err,
+			Delay:   policy.delay(attempt),
+		}
+
+		if resp != nil {
+			event.Status = resp.StatusCode
+			resp.Body.Close()
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(event)
+		}
+
+		timer := time.NewTimer(event.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
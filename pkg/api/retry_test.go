@@ -0,0 +1,289 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var (
+	errConnectionReset   = &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+)
+
+// NewFlakyRoundTrip returns a RoundTripFunc that fails with the given errors before calling fn
+// A nil error in failures responds with 503 Service Unavailable instead.
+func NewFlakyRoundTrip(fn RoundTripFunc, failures ...error) (RoundTripFunc, *int) {
+	attempts := 0
+
+	return func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts <= len(failures) {
+			err := failures[attempts-1]
+			if err == nil {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       ioutil.NopCloser(bytes.NewReader([]byte("<html>busy</html>"))),
+				}, nil
+			}
+
+			return nil, err
+		}
+
+		return fn(req)
+	}, &attempts
+}
+
+func testRetryPolicy(events *[]RetryEvent) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		OnRetry: func(event RetryEvent) {
+			*events = append(*events, event)
+		},
+	}
+}
+
+func TestRetryGetLights(t *testing.T) {
+	succeed := func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}, nil
+	}
+
+	tests := []struct {
+		name             string
+		failures         []error
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{name: "Test success on first attempt", expectedAttempts: 1},
+		{name: "Test connection reset is retried", failures: []error{errConnectionReset}, expectedAttempts: 2},
+		{name: "Test unavailable bridge is retried", failures: []error{nil, nil}, expectedAttempts: 3},
+		{name: "Test connection refused is retried", failures: []error{errConnectionRefused}, expectedAttempts: 2},
+		{
+			name:             "Test giving up after max attempts",
+			failures:         []error{errConnectionReset, errConnectionReset, errConnectionReset},
+			expectedAttempts: 3,
+			expectErr:        true,
+		},
+		{
+			name:             "Test permanent error is not retried",
+			failures:         []error{errors.New("Simulated failure")},
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip, attempts := NewFlakyRoundTrip(succeed, tt.failures...)
+
+			var events []RetryEvent
+			api := NewTestAPI(roundTrip, DefaultBrowse)
+			api.Retry = testRetryPolicy(&events)
+
+			bridge := Bridge{IP: []byte{127, 0, 0, 1}, API: api}
+
+			_, err := bridge.GetLights()
+			if tt.expectErr && err == nil {
+				t.Error("Expected an error to have been returned")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got %v", err)
+			}
+
+			if *attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts but got %d", tt.expectedAttempts, *attempts)
+			}
+
+			if len(events) != tt.expectedAttempts-1 {
+				t.Errorf("Expected %d retry events but got %d", tt.expectedAttempts-1, len(events))
+			}
+		})
+	}
+}
+
+func TestRetryConnect(t *testing.T) {
+	succeed := func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil || len(body) == 0 {
+			t.Errorf("Expected request body to be resent but got %q (%v)", body, err)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`[{"success": {"username": "testUser"}}]`))),
+		}, nil
+	}
+
+	tests := []struct {
+		name             string
+		failures         []error
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{name: "Test connection refused is retried", failures: []error{errConnectionRefused}, expectedAttempts: 2},
+		{name: "Test connection reset is not retried", failures: []error{errConnectionReset}, expectedAttempts: 1, expectErr: true},
+		{name: "Test unavailable bridge is not retried", failures: []error{nil}, expectedAttempts: 1, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip, attempts := NewFlakyRoundTrip(succeed, tt.failures...)
+
+			var events []RetryEvent
+			api := NewTestAPI(roundTrip, DefaultBrowse)
+			api.Retry = testRetryPolicy(&events)
+
+			bridge := Bridge{IP: []byte{127, 0, 0, 1}, API: api}
+
+			_, err := bridge.Connect()
+			if tt.expectErr && err == nil {
+				t.Error("Expected an error to have been returned")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got %v", err)
+			}
+
+			if *attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts but got %d", tt.expectedAttempts, *attempts)
+			}
+		})
+	}
+}
+
+func TestRetryToggleLight(t *testing.T) {
+	roundTrip, attempts := NewFlakyRoundTrip(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}, errConnectionReset)
+
+	var events []RetryEvent
+	api := NewTestAPI(roundTrip, DefaultBrowse)
+	api.Retry = testRetryPolicy(&events)
+
+	light := Light{
+		Type:   LightTypeDimmable,
+		Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+	}
+
+	err := light.ToggleLight()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if *attempts != 2 {
+		t.Errorf("Expected 2 attempts but got %d", *attempts)
+	}
+
+	if len(events) != 1 || !errors.Is(events[0].Err, syscall.ECONNRESET) || events[0].Attempt != 1 {
+		t.Errorf("Unexpected retry events %+v", events)
+	}
+
+	t.Run("Test relative change is not retried", func(t *testing.T) {
+		roundTrip, attempts := NewFlakyRoundTrip(DefaultRoundTrip, errConnectionReset)
+		light.Bridge.API = NewTestAPI(roundTrip, DefaultBrowse)
+		light.Bridge.API.Retry = testRetryPolicy(&events)
+
+		err := light.setState(NewStateBuilder().BrightnessDelta(10))
+		if !errors.Is(err, syscall.ECONNRESET) {
+			t.Errorf("Expected connection reset but got %v", err)
+		}
+
+		if *attempts != 1 {
+			t.Errorf("Expected 1 attempt but got %d", *attempts)
+		}
+	})
+}
+
+func TestRetryTimeout(t *testing.T) {
+	succeed := func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}, nil
+	}
+
+	// Blocks the first attempt until it is abandoned
+	hang := func(fn RoundTripFunc) (RoundTripFunc, *int) {
+		attempts := 0
+		return func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+
+			return fn(req)
+		}, &attempts
+	}
+
+	t.Run("Test client timeout is retried", func(t *testing.T) {
+		roundTrip, attempts := hang(succeed)
+
+		var events []RetryEvent
+		api := NewTestAPI(roundTrip, DefaultBrowse)
+		api.Client.Timeout = 20 * time.Millisecond
+		api.Retry = testRetryPolicy(&events)
+
+		bridge := Bridge{IP: []byte{127, 0, 0, 1}, API: api}
+
+		_, err := bridge.GetLights()
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+
+		if *attempts != 2 {
+			t.Errorf("Expected 2 attempts but got %d", *attempts)
+		}
+	})
+
+	t.Run("Test expired context is not retried", func(t *testing.T) {
+		roundTrip, attempts := hang(succeed)
+
+		var events []RetryEvent
+		api := NewTestAPI(roundTrip, DefaultBrowse)
+		api.Retry = testRetryPolicy(&events)
+
+		bridge := Bridge{IP: []byte{127, 0, 0, 1}, API: api}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		err := bridge.get(ctx, "/lights", &map[string]Light{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected %v but got %v", context.DeadlineExceeded, err)
+		}
+
+		if *attempts != 1 {
+			t.Errorf("Expected 1 attempt but got %d", *attempts)
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.delay(i + 1); got != want {
+			t.Errorf("Expected delay after attempt %d to be %v but got %v", i+1, want, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.delay(1)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("Expected jittered delay between 50ms and 150ms but got %v", got)
+		}
+	}
+}
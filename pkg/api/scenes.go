@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// Scene types reported by the bridge
+const (
+	SceneTypeLight = "LightScene"
+	SceneTypeGroup = "GroupScene"
+)
+
+// allLightsGroup is the ID of the special group containing every light on the bridge
+const allLightsGroup = "0"
+
+// Scene represents a preset state for a set of lights
+type Scene struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Group       string   `json:"group"` // Only set for GroupScene
+	Lights      []string `json:"lights"`
+	Owner       string   `json:"owner"`
+	Recycle     bool     `json:"recycle"`
+	Locked      bool     `json:"locked"`
+	Picture     string   `json:"picture"`
+	LastUpdated hueTime  `json:"lastupdated"`
+	Version     int      `json:"version"`
+	ID          string   `json:"-"`
+	Bridge      *Bridge  `json:"-"`
+}
+
+// sceneRecall represents the payload used to recall a scene through a group's action
+type sceneRecall struct {
+	Scene          string  `json:"scene"`
+	TransitionTime *uint16 `json:"transitiontime,omitempty"`
+}
+
+// GetScenes retrieves all the scenes on a certain bridge
+func (bridge *Bridge) GetScenes() ([]Scene, error) {
+	var data map[string]Scene
+	err := bridge.get(context.Background(), "/scenes", &data)
+	if err != nil {
+		return nil, err
+	}
+
+	scenes := []Scene{}
+
+	for id, scene := range data {
+		scene.ID = id
+		scene.Bridge = bridge
+		scenes = append(scenes, scene)
+	}
+
+	return scenes, nil
+}
+
+// RecallScene applies a scene to the lights of a group
+// Only the lights that are part of both the group and the scene are changed.
+func (group *Group) RecallScene(sceneID string, options ...StateOption) error {
+	return group.RecallSceneContext(context.Background(), sceneID, options...)
+}
+
+// RecallSceneContext is like RecallScene but sends the request with a context (see WithPriority)
+func (group *Group) RecallSceneContext(ctx context.Context, sceneID string, options ...StateOption) error {
+	update, err := NewStateBuilder().With(options...).Build(nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = group.Bridge.put(ctx, fmt.Sprintf("/groups/%s/action", group.ID), sceneRecall{
+		Scene:          sceneID,
+		TransitionTime: update.TransitionTime,
+	})
+
+	// There's no way to know what the scene changed without reading it back
+	group.Bridge.Cache.Invalidate(ResourceLights)
+	group.Bridge.Cache.Invalidate(ResourceGroups)
+
+	return err
+}
+
+// Recall applies a scene to its lights
+// Group scenes are recalled through their group. Light scenes are recalled through the group containing
+// every light on the bridge.
+func (scene *Scene) Recall(options ...StateOption) error {
+	group := Group{
+		ID:     allLightsGroup,
+		Bridge: scene.Bridge,
+	}
+
+	if scene.Type == SceneTypeGroup && scene.Group != "" {
+		group.ID = scene.Group
+	}
+
+	return group.RecallScene(scene.ID, options...)
+}
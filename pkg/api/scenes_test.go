@@ -0,0 +1,118 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetScenes(t *testing.T) {
+	api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+		json := `{
+			"4e1c6b20e-on-0": {
+				"name": "Energize",
+				"type": "GroupScene",
+				"group": "1",
+				"lights": ["1", "2"],
+				"owner": "ffffffffe0341b1b376a2389376a2389",
+				"recycle": false,
+				"locked": false,
+				"picture": "",
+				"lastupdated": "2020-11-05T19:42:27",
+				"version": 2
+			}
+		}`
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(json)),
+		}, nil
+	}, DefaultBrowse)
+
+	bridge := Bridge{
+		IP:  []byte{127, 0, 0, 1},
+		API: api,
+	}
+
+	scenes, err := bridge.GetScenes()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	expected := []Scene{
+		{
+			Name:        "Energize",
+			Type:        SceneTypeGroup,
+			Group:       "1",
+			Lights:      []string{"1", "2"},
+			Owner:       "ffffffffe0341b1b376a2389376a2389",
+			LastUpdated: hueTime{time.Date(2020, 11, 5, 19, 42, 27, 0, time.UTC)},
+			Version:     2,
+			ID:          "4e1c6b20e-on-0",
+			Bridge:      &bridge,
+		},
+	}
+
+	if diff := cmp.Diff(expected, scenes); diff != "" {
+		t.Errorf("Scenes mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecallScene(t *testing.T) {
+	tests := []struct {
+		name         string
+		scene        Scene
+		options      []StateOption
+		expectedPath string
+		expectedBody string
+	}{
+		{
+			name:         "Test group scene is recalled through its group",
+			scene:        Scene{ID: "abc", Type: SceneTypeGroup, Group: "3"},
+			expectedPath: "/api/user/groups/3/action",
+			expectedBody: `{"scene":"abc"}`,
+		},
+		{
+			name:         "Test light scene is recalled through all lights",
+			scene:        Scene{ID: "abc", Type: SceneTypeLight},
+			options:      []StateOption{WithTransition(3 * time.Second)},
+			expectedPath: "/api/user/groups/0/action",
+			expectedBody: `{"scene":"abc","transitiontime":30}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, body string
+			api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				path = req.URL.Path
+				body = string(data)
+
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			}, DefaultBrowse)
+
+			tt.scene.Bridge = &Bridge{IP: []byte{127, 0, 0, 1}, Username: "user", API: api}
+
+			err := tt.scene.Recall(tt.options...)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if path != tt.expectedPath {
+				t.Errorf("Expected request to %s but got %s", tt.expectedPath, path)
+			}
+
+			if !jsonEqual(t, body, tt.expectedBody) {
+				t.Errorf("Expected body %s but got %s", tt.expectedBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Schedule statuses reported by the bridge
+const (
+	ScheduleStatusEnabled  = "enabled"
+	ScheduleStatusDisabled = "disabled"
+)
+
+// ScheduleCommand represents the request a bridge sends to itself when a schedule triggers
+type ScheduleCommand struct {
+	Address string          `json:"address"` // For example `/api/<username>/groups/1/action`
+	Method  string          `json:"method"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// Schedule represents a command the bridge runs at a certain time
+// Schedules run on the bridge so they trigger even when no client is running.
+type Schedule struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Command     ScheduleCommand `json:"command"`
+	LocalTime   string          `json:"localtime"` // In the bridge's timezone (see ScheduleTime)
+	Status      string          `json:"status"`
+	AutoDelete  bool            `json:"autodelete"` // Whether the schedule is deleted once it triggers
+	Recycle     bool            `json:"recycle"`
+	Created     hueTime         `json:"created"`
+	ID          string          `json:"-"`
+	Bridge      *Bridge         `json:"-"`
+}
+
+// scheduleCreate represents the payload used to create a schedule (the bridge sets the other attributes)
+type scheduleCreate struct {
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Command     ScheduleCommand `json:"command"`
+	LocalTime   string          `json:"localtime"`
+	Status      string          `json:"status,omitempty"`
+	AutoDelete  bool            `json:"autodelete"`
+	Recycle     bool            `json:"recycle,omitempty"`
+}
+
+// ScheduleTime formats a time for a schedule that triggers once
+// The bridge interprets the time in its own timezone, so t should be in the bridge's location.
+func ScheduleTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05")
+}
+
+// ActionCommand builds the command that sends a state update to the group when a schedule triggers
+func (group *Group) ActionCommand(update LightStateUpdate) (ScheduleCommand, error) {
+	err := update.Validate(nil)
+	if err != nil {
+		return ScheduleCommand{}, err
+	}
+
+	body, err := json.Marshal(update)
+	if err != nil {
+		return ScheduleCommand{}, err
+	}
+
+	return ScheduleCommand{
+		Address: fmt.Sprintf("/api/%s/groups/%s/action", group.Bridge.Username, group.ID),
+		Method:  http.MethodPut,
+		Body:    body,
+	}, nil
+}
+
+// GetSchedules retrieves all the schedules on a certain bridge
+func (bridge *Bridge) GetSchedules() ([]Schedule, error) {
+	var data map[string]Schedule
+	err := bridge.get(context.Background(), "/schedules", &data)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []Schedule{}
+
+	for id, schedule := range data {
+		schedule.ID = id
+		schedule.Bridge = bridge
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+// CreateSchedule creates a schedule on the bridge
+// The ID the bridge assigned to the schedule is returned. Only the name, description, command, local time,
+// status, autodelete and recycle attributes are sent.
+func (bridge *Bridge) CreateSchedule(ctx context.Context, schedule Schedule) (string, error) {
+	created, err := bridge.post(ctx, "/schedules", scheduleCreate{
+		Name:        schedule.Name,
+		Description: schedule.Description,
+		Command:     schedule.Command,
+		LocalTime:   schedule.LocalTime,
+		Status:      schedule.Status,
+		AutoDelete:  schedule.AutoDelete,
+		Recycle:     schedule.Recycle,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	err = json.Unmarshal(created["id"], &id)
+	if err != nil {
+		return "", fmt.Errorf("Bridge did not report the new schedule's ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// Delete removes the schedule from the bridge
+func (schedule *Schedule) Delete(ctx context.Context) error {
+	return schedule.Bridge.delete(ctx, fmt.Sprintf("/schedules/%s", schedule.ID))
+}
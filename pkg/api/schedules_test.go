@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+)
+
+func TestScheduleTime(t *testing.T) {
+	at := time.Date(2021, 3, 1, 6, 30, 5, 999, time.FixedZone("PST", -8*60*60))
+
+	if formatted := api.ScheduleTime(at); formatted != "2021-03-01T06:30:05" {
+		t.Errorf("Expected `2021-03-01T06:30:05` but got `%s`", formatted)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	bridge := server.Endpoint(&api.API{})
+	bridge.Username = server.Bridge.Register("hugh#test")
+
+	groups, err := bridge.GetGroups()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	group := groups[0]
+
+	on := true
+	command, err := group.ActionCommand(api.LightStateUpdate{On: &on})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	t.Run("Test invalid command", func(t *testing.T) {
+		brightness := uint8(0)
+		_, err := group.ActionCommand(api.LightStateUpdate{Brightness: &brightness})
+
+		var validationErr *api.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected a validation error but got %v", err)
+		}
+	})
+
+	t.Run("Test create, get and delete", func(t *testing.T) {
+		id, err := bridge.CreateSchedule(context.Background(), api.Schedule{
+			Name:      "Lights on",
+			Command:   command,
+			LocalTime: api.ScheduleTime(time.Now().Add(time.Hour)),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		schedules, err := bridge.GetSchedules()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if len(schedules) != 1 || schedules[0].ID != id || schedules[0].Name != "Lights on" || schedules[0].Created.IsZero() {
+			t.Fatalf("Expected the new schedule but got %+v", schedules)
+		}
+
+		if schedules[0].Command.Address != "/api/"+bridge.Username+"/groups/"+group.ID+"/action" {
+			t.Errorf("Unexpected command address `%s`", schedules[0].Command.Address)
+		}
+
+		err = schedules[0].Delete(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		err = schedules[0].Delete(context.Background())
+		if !errors.Is(err, api.ErrResourceNotAvailable) {
+			t.Errorf("Expected %v but got %v", api.ErrResourceNotAvailable, err)
+		}
+	})
+
+	t.Run("Test invalid local time", func(t *testing.T) {
+		_, err := bridge.CreateSchedule(context.Background(), api.Schedule{Command: command, LocalTime: "tomorrow"})
+
+		var apiErr api.APIError
+		if !errors.As(err, &apiErr) || apiErr.Type != api.ErrorTypeInvalidValue {
+			t.Errorf("Expected an invalid value error but got %v", err)
+		}
+	})
+}
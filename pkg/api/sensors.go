@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+)
+
+// Sensor represents a sensor (motion sensor, switch, daylight sensor, etc.)
+// The attributes in a sensor's state and config depend on its type so they are kept as maps.
+type Sensor struct {
+	State        map[string]interface{} `json:"state"`
+	Config       map[string]interface{} `json:"config"`
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	ModelID      string                 `json:"modelid"`
+	Manufacturer string                 `json:"manufacturername"`
+	Product      string                 `json:"productname"`
+	UID          string                 `json:"uniqueid"`
+	SWVersion    string                 `json:"swversion"`
+	ID           string                 `json:"-"`
+	Bridge       *Bridge                `json:"-"`
+}
+
+// GetSensors retrieves all the sensors on a certain bridge
+func (bridge *Bridge) GetSensors() ([]Sensor, error) {
+	data, ok := bridge.Cache.cachedSensors()
+	if !ok {
+		err := bridge.get(context.Background(), "/sensors", &data)
+		if err != nil {
+			return nil, err
+		}
+
+		bridge.Cache.storeSensors(data)
+	}
+
+	sensors := []Sensor{}
+
+	for id, sensor := range data {
+		sensor.ID = id
+		sensor.Bridge = bridge
+		sensors = append(sensors, sensor)
+	}
+
+	return sensors, nil
+}
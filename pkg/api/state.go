@@ -0,0 +1,553 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Limits on the values the bridge accepts for a light's state
+const (
+	MinimumBrightness     = 1
+	MaximumBrightness     = 254
+	MaximumSaturation     = 254
+	MaximumHue            = 65535
+	MaximumTransitionTime = 65535 * TransitionTimeUnit
+
+	// Limits on relative changes
+	MaximumBrightnessDelta  = 254
+	MaximumSaturationDelta  = 254
+	MaximumHueDelta         = 65534
+	MaximumTemperatureDelta = 65534
+	MaximumCIEDelta         = 0.5
+
+	// TransitionTimeUnit is the resolution of transition times sent to the bridge
+	TransitionTimeUnit = 100 * time.Millisecond
+)
+
+// Valid values for a light's alert attribute
+const (
+	AlertNone   = "none"
+	AlertSelect = "select"
+	AlertLong   = "lselect"
+)
+
+// Valid values for a light's effect attribute
+const (
+	EffectNone      = "none"
+	EffectColorLoop = "colorloop"
+)
+
+// DefaultTemperatureRange is the color temperature range of standard Hue bulbs
+// It is used for lights that don't report a range and when validating a state for an unknown light or a group.
+var DefaultTemperatureRange = LightTemperatureRange{Minimum: 153, Maximum: 500}
+
+// LightStateUpdate represents a change to a light's state
+// Only attributes that are set (non-nil) are sent to the bridge. Use a StateBuilder to construct a
+// validated update.
+type LightStateUpdate struct {
+	On             *bool     `json:"on,omitempty"`
+	Brightness     *uint8    `json:"bri,omitempty"`
+	Hue            *uint16   `json:"hue,omitempty"`
+	Saturation     *uint8    `json:"sat,omitempty"`
+	CIECoords      *CIECoord `json:"xy,omitempty"`
+	Temperature    *uint16   `json:"ct,omitempty"`
+	Alert          *string   `json:"alert,omitempty"`
+	Effect         *string   `json:"effect,omitempty"`
+	TransitionTime *uint16   `json:"transitiontime,omitempty"` // Values are multiples of 100ms and default is 4 (400ms)
+
+	// Deltas are ignored by the bridge when the corresponding absolute value is also set.
+	// Setting a delta value to 0 stops any ongoing transition.
+
+	BrightnessDelta  *int16    `json:"bri_inc,omitempty"` // Valid values are -254 to 254
+	SaturationDelta  *int16    `json:"sat_inc,omitempty"` // Valid values are -254 to 254
+	HueDelta         *int32    `json:"hue_inc,omitempty"` // Valid values are -65534 to 65534
+	TemperatureDelta *int32    `json:"ct_inc,omitempty"`  // Valid values are -65534 to 65534
+	CIEDelta         *CIECoord `json:"xy_inc,omitempty"`  // Valid values are -0.5 to 0.5 for both coordinates
+}
+
+// FieldError describes why a single attribute of a state is invalid
+type FieldError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+}
+
+func (err FieldError) Error() string {
+	return fmt.Sprintf("%s: `%v` %s", err.Field, err.Value, err.Reason)
+}
+
+// ValidationError is returned when a state has one or more invalid attributes
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (err *ValidationError) Error() string {
+	messages := make([]string, len(err.Fields))
+	for i, field := range err.Fields {
+		messages[i] = field.Error()
+	}
+
+	return fmt.Sprintf("Invalid light state (%s)", strings.Join(messages, "; "))
+}
+
+func (err *ValidationError) add(field string, value interface{}, format string, args ...interface{}) {
+	err.Fields = append(err.Fields, FieldError{
+		Field:  field,
+		Value:  value,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// errOrNil returns nil when no fields were invalid so callers don't end up with a non-nil error interface
+func (err *ValidationError) errOrNil() error {
+	if len(err.Fields) == 0 {
+		return nil
+	}
+
+	return err
+}
+
+// Validate checks that every attribute set in the update is within the range the bridge accepts
+// The color temperature is checked against the light's range. A nil light is validated against the
+// range of standard Hue bulbs (153 to 500).
+func (update LightStateUpdate) Validate(light *Light) error {
+	errs := &ValidationError{}
+	update.validate(light, errs)
+
+	return errs.errOrNil()
+}
+
+func (update LightStateUpdate) validate(light *Light, errs *ValidationError) {
+	tempRange := light.TemperatureRange()
+
+	if update.Brightness != nil && (*update.Brightness < MinimumBrightness || *update.Brightness > MaximumBrightness) {
+		errs.add("bri", *update.Brightness, "must be between %d and %d", MinimumBrightness, MaximumBrightness)
+	}
+
+	if update.Saturation != nil && *update.Saturation > MaximumSaturation {
+		errs.add("sat", *update.Saturation, "must be between 0 and %d", MaximumSaturation)
+	}
+
+	if update.CIECoords != nil {
+		for _, coord := range update.CIECoords {
+			if coord < 0 || coord > 1 {
+				errs.add("xy", *update.CIECoords, "coordinates must be between 0 and 1")
+				break
+			}
+		}
+	}
+
+	if update.Temperature != nil {
+		mired := uint(*update.Temperature)
+		if mired < tempRange.Minimum || mired > tempRange.Maximum {
+			errs.add("ct", mired, "must be between %d and %d", tempRange.Minimum, tempRange.Maximum)
+		}
+	}
+
+	if update.BrightnessDelta != nil && abs(int64(*update.BrightnessDelta)) > MaximumBrightnessDelta {
+		errs.add("bri_inc", *update.BrightnessDelta, "must be between %d and %d", -MaximumBrightnessDelta, MaximumBrightnessDelta)
+	}
+
+	if update.SaturationDelta != nil && abs(int64(*update.SaturationDelta)) > MaximumSaturationDelta {
+		errs.add("sat_inc", *update.SaturationDelta, "must be between %d and %d", -MaximumSaturationDelta, MaximumSaturationDelta)
+	}
+
+	if update.HueDelta != nil && abs(int64(*update.HueDelta)) > MaximumHueDelta {
+		errs.add("hue_inc", *update.HueDelta, "must be between %d and %d", -MaximumHueDelta, MaximumHueDelta)
+	}
+
+	if update.TemperatureDelta != nil && abs(int64(*update.TemperatureDelta)) > MaximumTemperatureDelta {
+		errs.add("ct_inc", *update.TemperatureDelta, "must be between %d and %d", -MaximumTemperatureDelta, MaximumTemperatureDelta)
+	}
+
+	if update.CIEDelta != nil {
+		for _, delta := range update.CIEDelta {
+			if math.Abs(delta) > MaximumCIEDelta {
+				errs.add("xy_inc", *update.CIEDelta, "deltas must be between %g and %g", -MaximumCIEDelta, MaximumCIEDelta)
+				break
+			}
+		}
+	}
+
+	if update.Alert != nil {
+		switch *update.Alert {
+		case AlertNone, AlertSelect, AlertLong:
+		default:
+			errs.add("alert", *update.Alert, "must be one of %q, %q or %q", AlertNone, AlertSelect, AlertLong)
+		}
+	}
+
+	if update.Effect != nil {
+		switch *update.Effect {
+		case EffectNone, EffectColorLoop:
+		default:
+			errs.add("effect", *update.Effect, "must be one of %q or %q", EffectNone, EffectColorLoop)
+		}
+	}
+}
+
+// hasDeltas returns true if the update changes any attribute relative to its current value
+func (update LightStateUpdate) hasDeltas() bool {
+	return update.BrightnessDelta != nil ||
+		update.SaturationDelta != nil ||
+		update.HueDelta != nil ||
+		update.TemperatureDelta != nil ||
+		update.CIEDelta != nil
+}
+
+// requiredFeatures returns the light features needed to apply the update
+func (update LightStateUpdate) requiredFeatures() Feature {
+	var features Feature
+
+	if update.On != nil {
+		features |= FeatureOnOff
+	}
+
+	if update.Brightness != nil || update.BrightnessDelta != nil {
+		features |= FeatureBrightness
+	}
+
+	if update.Hue != nil || update.Saturation != nil || update.CIECoords != nil {
+		features |= FeatureColor
+	}
+
+	if update.HueDelta != nil || update.SaturationDelta != nil || update.CIEDelta != nil {
+		features |= FeatureColor
+	}
+
+	if update.Effect != nil && *update.Effect == EffectColorLoop {
+		features |= FeatureColor
+	}
+
+	if update.Temperature != nil || update.TemperatureDelta != nil {
+		features |= FeatureColorTemperature
+	}
+
+	return features
+}
+
+// apply updates a light's state with the values that were set in an update
+// Deltas are applied the way the bridge applies them: brightness, saturation, color temperature and CIE
+// coordinates are clipped to their ranges while hue wraps around. Deltas are ignored when the corresponding
+// absolute value is also set.
+func (state *LightState) apply(update LightStateUpdate, tempRange LightTemperatureRange) {
+	if update.On != nil {
+		state.On = *update.On
+	}
+
+	if update.Brightness != nil {
+		state.Brightness = *update.Brightness
+	} else if update.BrightnessDelta != nil {
+		state.Brightness = uint8(clamp(int64(state.Brightness)+int64(*update.BrightnessDelta), MinimumBrightness, MaximumBrightness))
+	}
+
+	if update.Hue != nil {
+		state.Hue = *update.Hue
+		state.ColorMode = "hs"
+	} else if update.HueDelta != nil {
+		state.Hue = uint16((int64(state.Hue) + int64(*update.HueDelta)) & MaximumHue)
+		state.ColorMode = "hs"
+	}
+
+	if update.Saturation != nil {
+		state.Saturation = *update.Saturation
+		state.ColorMode = "hs"
+	} else if update.SaturationDelta != nil {
+		state.Saturation = uint8(clamp(int64(state.Saturation)+int64(*update.SaturationDelta), 0, MaximumSaturation))
+		state.ColorMode = "hs"
+	}
+
+	if update.Temperature != nil {
+		state.Temperature = *update.Temperature
+		state.ColorMode = "ct"
+	} else if update.TemperatureDelta != nil {
+		temperature := clamp(int64(state.Temperature)+int64(*update.TemperatureDelta), int64(tempRange.Minimum), int64(tempRange.Maximum))
+		state.Temperature = uint16(temperature)
+		state.ColorMode = "ct"
+	}
+
+	// The bridge gives xy precedence over ct and hs
+	if update.CIECoords != nil {
+		state.CIECoords = *update.CIECoords
+		state.ColorMode = "xy"
+	} else if update.CIEDelta != nil {
+		for i, delta := range update.CIEDelta {
+			state.CIECoords[i] = roundCIE(clampFloat(state.CIECoords[i]+delta, 0, 1))
+		}
+		state.ColorMode = "xy"
+	}
+
+	if update.Alert != nil {
+		state.Alert = *update.Alert
+	}
+
+	if update.Effect != nil {
+		state.Effect = *update.Effect
+	}
+}
+
+// reconcile updates a state with the absolute values reported by the bridge after an update
+// The bridge reports values keyed by their address (for example `/lights/1/state/bri`). Only the
+// last element of the address is used. Deltas and transition times are not part of a state and are ignored.
+func (state *LightState) reconcile(updated map[string]json.RawMessage) error {
+	values := map[string]json.RawMessage{}
+	for address, value := range updated {
+		attribute := address[strings.LastIndex(address, "/")+1:]
+		if strings.HasSuffix(attribute, "_inc") || attribute == "transitiontime" {
+			continue
+		}
+
+		values[attribute] = value
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	// Unmarshalling into an existing struct only overwrites the attributes present in the data
+	return json.Unmarshal(data, state)
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+func clampFloat(value, minimum, maximum float64) float64 {
+	return math.Max(minimum, math.Min(maximum, value))
+}
+
+func clamp(value, minimum, maximum int64) int64 {
+	if value < minimum {
+		return minimum
+	}
+
+	if value > maximum {
+		return maximum
+	}
+
+	return value
+}
+
+// StateBuilder constructs a LightStateUpdate and validates it
+// Invalid values are recorded rather than rejected immediately so every problem is reported by Build.
+type StateBuilder struct {
+	update LightStateUpdate
+	errs   ValidationError
+}
+
+// NewStateBuilder returns a builder for an empty state update
+func NewStateBuilder() *StateBuilder {
+	return &StateBuilder{}
+}
+
+// On sets whether the light should be on
+func (builder *StateBuilder) On(on bool) *StateBuilder {
+	builder.update.On = &on
+	return builder
+}
+
+// Brightness sets the light's brightness (1 to 254)
+func (builder *StateBuilder) Brightness(brightness int) *StateBuilder {
+	if brightness < MinimumBrightness || brightness > MaximumBrightness {
+		builder.errs.add("bri", brightness, "must be between %d and %d", MinimumBrightness, MaximumBrightness)
+		return builder
+	}
+
+	value := uint8(brightness)
+	builder.update.Brightness = &value
+	return builder
+}
+
+// Hue sets the light's hue (0 to 65535)
+func (builder *StateBuilder) Hue(hue int) *StateBuilder {
+	if hue < 0 || hue > MaximumHue {
+		builder.errs.add("hue", hue, "must be between 0 and %d", MaximumHue)
+		return builder
+	}
+
+	value := uint16(hue)
+	builder.update.Hue = &value
+	return builder
+}
+
+// Saturation sets the light's saturation (0 to 254)
+func (builder *StateBuilder) Saturation(saturation int) *StateBuilder {
+	if saturation < 0 || saturation > MaximumSaturation {
+		builder.errs.add("sat", saturation, "must be between 0 and %d", MaximumSaturation)
+		return builder
+	}
+
+	value := uint8(saturation)
+	builder.update.Saturation = &value
+	return builder
+}
+
+// CIECoords sets the light's color as coordinates in the CIE color space (both between 0 and 1)
+func (builder *StateBuilder) CIECoords(x, y float64) *StateBuilder {
+	coords := CIECoord{x, y}
+	builder.update.CIECoords = &coords
+	return builder
+}
+
+// Temperature sets the light's color temperature in mireds
+// The value is checked against the light's temperature range when the update is built.
+func (builder *StateBuilder) Temperature(mired int) *StateBuilder {
+	if mired < 0 || mired > int(^uint16(0)) {
+		builder.errs.add("ct", mired, "must be between 0 and %d", ^uint16(0))
+		return builder
+	}
+
+	value := uint16(mired)
+	builder.update.Temperature = &value
+	return builder
+}
+
+// Alert sets the light's alert effect ("none", "select" or "lselect")
+func (builder *StateBuilder) Alert(alert string) *StateBuilder {
+	builder.update.Alert = &alert
+	return builder
+}
+
+// Effect sets the light's dynamic effect ("none" or "colorloop")
+func (builder *StateBuilder) Effect(effect string) *StateBuilder {
+	builder.update.Effect = &effect
+	return builder
+}
+
+// TransitionTime sets how long the light should take to transition to the new state
+// The bridge only supports multiples of 100ms up to 65535 * 100ms so the duration is rounded to the
+// nearest 100ms. The bridge's default of 400ms is used if a transition time is never set.
+func (builder *StateBuilder) TransitionTime(transition time.Duration) *StateBuilder {
+	value, err := transitionTimeUnits(transition)
+	if err != nil {
+		builder.errs.Fields = append(builder.errs.Fields, *err)
+		return builder
+	}
+
+	builder.update.TransitionTime = &value
+	return builder
+}
+
+// TransitionTimeUnits converts a duration to the bridge's transition time units (multiples of 100ms)
+// The duration is rounded to the nearest 100ms and clamped between 0 and MaximumTransitionTime.
+func TransitionTimeUnits(transition time.Duration) uint16 {
+	if transition <= 0 {
+		return 0
+	}
+
+	if transition >= MaximumTransitionTime {
+		return uint16(MaximumTransitionTime / TransitionTimeUnit)
+	}
+
+	return uint16((transition + TransitionTimeUnit/2) / TransitionTimeUnit)
+}
+
+// transitionTimeUnits converts a duration to the bridge's transition time units like TransitionTimeUnits
+// Durations that don't round to a value the bridge accepts are rejected instead of being clamped.
+func transitionTimeUnits(transition time.Duration) (uint16, *FieldError) {
+	units := (transition + TransitionTimeUnit/2) / TransitionTimeUnit
+	if transition < 0 || units > MaximumTransitionTime/TransitionTimeUnit {
+		return 0, &FieldError{
+			Field:  "transitiontime",
+			Value:  transition,
+			Reason: fmt.Sprintf("must be between 0s and %s", MaximumTransitionTime),
+		}
+	}
+
+	return TransitionTimeUnits(transition), nil
+}
+
+// StateOption modifies a state change made through one of the convenience setters (SetOn, SetBrightness, etc.)
+type StateOption func(*StateBuilder)
+
+// WithTransition sets how long a state change should take
+// Without this option the bridge's default of 400ms is used.
+func WithTransition(transition time.Duration) StateOption {
+	return func(builder *StateBuilder) {
+		builder.TransitionTime(transition)
+	}
+}
+
+// With applies options to the builder
+func (builder *StateBuilder) With(options ...StateOption) *StateBuilder {
+	for _, option := range options {
+		option(builder)
+	}
+
+	return builder
+}
+
+// BrightnessDelta changes the light's brightness relative to its current brightness
+// Deltas beyond -254 to 254 are clamped.
+func (builder *StateBuilder) BrightnessDelta(delta int) *StateBuilder {
+	value := int16(clamp(int64(delta), -MaximumBrightnessDelta, MaximumBrightnessDelta))
+	builder.update.BrightnessDelta = &value
+	return builder
+}
+
+// SaturationDelta changes the light's saturation relative to its current saturation
+// Deltas beyond -254 to 254 are clamped.
+func (builder *StateBuilder) SaturationDelta(delta int) *StateBuilder {
+	value := int16(clamp(int64(delta), -MaximumSaturationDelta, MaximumSaturationDelta))
+	builder.update.SaturationDelta = &value
+	return builder
+}
+
+// HueDelta changes the light's hue relative to its current hue
+// Deltas beyond -65534 to 65534 are clamped.
+func (builder *StateBuilder) HueDelta(delta int) *StateBuilder {
+	value := int32(clamp(int64(delta), -MaximumHueDelta, MaximumHueDelta))
+	builder.update.HueDelta = &value
+	return builder
+}
+
+// TemperatureDelta changes the light's color temperature (in mireds) relative to its current color temperature
+// Deltas beyond -65534 to 65534 are clamped.
+func (builder *StateBuilder) TemperatureDelta(delta int) *StateBuilder {
+	value := int32(clamp(int64(delta), -MaximumTemperatureDelta, MaximumTemperatureDelta))
+	builder.update.TemperatureDelta = &value
+	return builder
+}
+
+// CIEDelta changes the light's CIE coordinates relative to its current coordinates
+// Deltas beyond -0.5 to 0.5 are clamped.
+func (builder *StateBuilder) CIEDelta(x, y float64) *StateBuilder {
+	delta := CIECoord{
+		clampFloat(x, -MaximumCIEDelta, MaximumCIEDelta),
+		clampFloat(y, -MaximumCIEDelta, MaximumCIEDelta),
+	}
+	builder.update.CIEDelta = &delta
+	return builder
+}
+
+// StopTransition stops any transition that is in progress
+// The bridge stops transitions when it is sent a delta of 0.
+func (builder *StateBuilder) StopTransition() *StateBuilder {
+	return builder.BrightnessDelta(0)
+}
+
+// Build validates the update against a light and returns it
+// A nil light is validated against the range of standard Hue bulbs. If any attribute is invalid a
+// *ValidationError listing every invalid attribute is returned.
+func (builder *StateBuilder) Build(light *Light) (LightStateUpdate, error) {
+	errs := &ValidationError{Fields: append([]FieldError{}, builder.errs.Fields...)}
+	builder.update.validate(light, errs)
+
+	if err := errs.errOrNil(); err != nil {
+		return LightStateUpdate{}, err
+	}
+
+	return builder.update, nil
+}
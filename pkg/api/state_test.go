@@ -0,0 +1,387 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStateBuilder(t *testing.T) {
+	light := &Light{
+		Capabilities: LightCapabilities{
+			Control: LightCapabilitiesControl{
+				TemperatureRange: LightTemperatureRange{Minimum: 153, Maximum: 454},
+			},
+		},
+	}
+
+	t.Run("Test valid state is built", func(t *testing.T) {
+		update, err := NewStateBuilder().
+			On(true).
+			Brightness(254).
+			Saturation(0).
+			CIECoords(0.3, 0.3).
+			Temperature(454).
+			Alert(AlertSelect).
+			Effect(EffectColorLoop).
+			TransitionTime(2 * time.Second).
+			Build(light)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if *update.Brightness != 254 {
+			t.Errorf("Expected brightness to be 254 but got %d", *update.Brightness)
+		}
+
+		if *update.TransitionTime != 20 {
+			t.Errorf("Expected transition time to be 20 but got %d", *update.TransitionTime)
+		}
+	})
+
+	t.Run("Test every invalid field is reported", func(t *testing.T) {
+		_, err := NewStateBuilder().
+			Brightness(0).
+			Hue(70000).
+			Saturation(255).
+			CIECoords(1.2, 0.3).
+			Temperature(500).
+			Alert("blink").
+			Effect("strobe").
+			TransitionTime(2 * time.Hour).
+			Build(light)
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected a validation error but got %v", err)
+		}
+
+		var fields []string
+		for _, field := range validationErr.Fields {
+			fields = append(fields, field.Field)
+		}
+
+		expected := []string{"bri", "hue", "sat", "transitiontime", "xy", "ct", "alert", "effect"}
+		if diff := cmp.Diff(expected, fields); diff != "" {
+			t.Errorf("Invalid fields mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Test temperature outside of the attribute's range", func(t *testing.T) {
+		_, err := NewStateBuilder().Temperature(70000).Build(nil)
+
+		expected := "Invalid light state (ct: `70000` must be between 0 and 65535)"
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected `%s` but got %v", expected, err)
+		}
+	})
+
+	t.Run("Test temperature is checked against default range without light", func(t *testing.T) {
+		_, err := NewStateBuilder().Temperature(500).Build(nil)
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+	})
+}
+
+func TestSetState(t *testing.T) {
+	t.Run("Test only set attributes are sent", func(t *testing.T) {
+		var body string
+		api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+			data, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			body = string(data)
+
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}, DefaultBrowse)
+
+		light := Light{
+			Type:   LightTypeExtendedColor,
+			Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+		}
+
+		update, err := NewStateBuilder().On(true).Brightness(100).CIECoords(0.5, 0.4).Build(&light)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		err = light.SetState(update)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		expectedBody := `{"on":true,"bri":100,"xy":[0.5,0.4]}`
+		if !jsonEqual(t, body, expectedBody) {
+			t.Errorf("Expected body %s but got %s", expectedBody, body)
+		}
+
+		expectedState := LightState{On: true, Brightness: 100, CIECoords: CIECoord{0.5, 0.4}, ColorMode: "xy"}
+		if diff := cmp.Diff(expectedState, light.State); diff != "" {
+			t.Errorf("State mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Test invalid update is not sent", func(t *testing.T) {
+		light := Light{
+			Type:   LightTypeDimmable,
+			Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: NewTestAPI(DefaultRoundTrip, DefaultBrowse)},
+		}
+
+		brightness := uint8(255)
+		err := light.SetState(LightStateUpdate{Brightness: &brightness})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected a validation error but got %v", err)
+		}
+	})
+}
+
+func TestRelativeStateChanges(t *testing.T) {
+	tests := []struct {
+		name          string
+		initial       LightState
+		builder       *StateBuilder
+		response      string
+		expectedBody  string
+		expectedState LightState
+	}{
+		{
+			name:          "Test brightness is clipped",
+			initial:       LightState{Brightness: 200},
+			builder:       NewStateBuilder().BrightnessDelta(100),
+			expectedBody:  `{"bri_inc":100}`,
+			expectedState: LightState{Brightness: 254},
+		},
+		{
+			name:          "Test deltas beyond range are clamped",
+			initial:       LightState{Brightness: 100, Saturation: 100},
+			builder:       NewStateBuilder().BrightnessDelta(-1000).SaturationDelta(-1000),
+			expectedBody:  `{"bri_inc":-254,"sat_inc":-254}`,
+			expectedState: LightState{Brightness: 1, Saturation: 0, ColorMode: "hs"},
+		},
+		{
+			name:          "Test hue wraps",
+			initial:       LightState{Hue: 65000},
+			builder:       NewStateBuilder().HueDelta(1000),
+			expectedBody:  `{"hue_inc":1000}`,
+			expectedState: LightState{Hue: 464, ColorMode: "hs"},
+		},
+		{
+			name:          "Test color temperature is clipped to light's range",
+			initial:       LightState{Temperature: 400},
+			builder:       NewStateBuilder().TemperatureDelta(100),
+			expectedBody:  `{"ct_inc":100}`,
+			expectedState: LightState{Temperature: 454, ColorMode: "ct"},
+		},
+		{
+			name:          "Test CIE coordinates are clipped",
+			initial:       LightState{CIECoords: CIECoord{0.9, 0.2}},
+			builder:       NewStateBuilder().CIEDelta(0.2, -0.1),
+			expectedBody:  `{"xy_inc":[0.2,-0.1]}`,
+			expectedState: LightState{CIECoords: CIECoord{1, 0.1}, ColorMode: "xy"},
+		},
+		{
+			name:          "Test absolute value takes precedence over delta",
+			initial:       LightState{Brightness: 100},
+			builder:       NewStateBuilder().Brightness(50).BrightnessDelta(10),
+			expectedBody:  `{"bri":50,"bri_inc":10}`,
+			expectedState: LightState{Brightness: 50},
+		},
+		{
+			name:          "Test stopping a transition",
+			initial:       LightState{Brightness: 100},
+			builder:       NewStateBuilder().StopTransition(),
+			expectedBody:  `{"bri_inc":0}`,
+			expectedState: LightState{Brightness: 100},
+		},
+		{
+			name:          "Test state is reconciled with bridge response",
+			initial:       LightState{Brightness: 100},
+			builder:       NewStateBuilder().BrightnessDelta(10),
+			response:      `[{"success":{"/lights/1/state/bri":120}}]`,
+			expectedBody:  `{"bri_inc":10}`,
+			expectedState: LightState{Brightness: 120},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				body = string(data)
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader(tt.response)),
+				}, nil
+			}, DefaultBrowse)
+
+			light := Light{
+				ID:    "1",
+				Type:  LightTypeExtendedColor,
+				State: tt.initial,
+				Capabilities: LightCapabilities{
+					Control: LightCapabilitiesControl{
+						TemperatureRange: LightTemperatureRange{Minimum: 153, Maximum: 454},
+					},
+				},
+				Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+			}
+
+			err := light.setState(tt.builder)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if !jsonEqual(t, body, tt.expectedBody) {
+				t.Errorf("Expected body %s but got %s", tt.expectedBody, body)
+			}
+
+			if diff := cmp.Diff(tt.expectedState, light.State); diff != "" {
+				t.Errorf("State mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Test bridge error is returned", func(t *testing.T) {
+		api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+			json := `[
+				{"success":{"/lights/1/state/on":true}},
+				{"error":{"type":201,"address":"/lights/1/state/bri_inc","description":"parameter, bri_inc, is not modifiable. Device is set to off."}}
+			]`
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(json)),
+			}, nil
+		}, DefaultBrowse)
+
+		light := Light{
+			ID:     "1",
+			Type:   LightTypeDimmable,
+			State:  LightState{Brightness: 100},
+			Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api},
+		}
+
+		err := light.setState(NewStateBuilder().On(true).BrightnessDelta(10))
+
+		var apiErr APIError
+		if !errors.As(err, &apiErr) || apiErr.Type != 201 {
+			t.Fatalf("Expected an API error but got %v", err)
+		}
+
+		expectedState := LightState{On: true, Brightness: 100}
+		if diff := cmp.Diff(expectedState, light.State); diff != "" {
+			t.Errorf("State mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestTransitionTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []StateOption
+		expectedBody string
+		expectErr    bool
+	}{
+		{
+			name:         "Test transition time is omitted when unset",
+			expectedBody: `{"on":true}`,
+		},
+		{
+			name:         "Test instant transition",
+			options:      []StateOption{WithTransition(0)},
+			expectedBody: `{"on":true,"transitiontime":0}`,
+		},
+		{
+			name:         "Test transition time is rounded to 100ms",
+			options:      []StateOption{WithTransition(1260 * time.Millisecond)},
+			expectedBody: `{"on":true,"transitiontime":13}`,
+		},
+		{
+			name:         "Test maximum transition time",
+			options:      []StateOption{WithTransition(MaximumTransitionTime)},
+			expectedBody: `{"on":true,"transitiontime":65535}`,
+		},
+		{
+			name:      "Test transition time beyond maximum",
+			options:   []StateOption{WithTransition(MaximumTransitionTime + time.Second)},
+			expectErr: true,
+		},
+		{
+			name:      "Test negative transition time",
+			options:   []StateOption{WithTransition(-time.Second)},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			api := NewTestAPI(func(req *http.Request) (*http.Response, error) {
+				data, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				body = string(data)
+
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			}, DefaultBrowse)
+
+			light := Light{Bridge: &Bridge{IP: []byte{127, 0, 0, 1}, API: api}}
+
+			err := light.SetOn(true, tt.options...)
+			if tt.expectErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Expected a validation error but got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if !jsonEqual(t, body, tt.expectedBody) {
+				t.Errorf("Expected body %s but got %s", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestTransitionTimeUnits(t *testing.T) {
+	tests := []struct {
+		name       string
+		transition time.Duration
+		units      uint16
+	}{
+		{name: "Test zero", transition: 0, units: 0},
+		{name: "Test rounding down", transition: 1240 * time.Millisecond, units: 12},
+		{name: "Test rounding up", transition: 1250 * time.Millisecond, units: 13},
+		{name: "Test maximum transition time", transition: MaximumTransitionTime, units: 65535},
+		{name: "Test transition time beyond maximum is clamped", transition: 2 * time.Hour, units: 65535},
+		{name: "Test negative transition time is clamped", transition: -time.Second, units: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TransitionTimeUnits(tt.transition)
+			if got != tt.units {
+				t.Errorf("Expected %d units but got %d", tt.units, got)
+			}
+		})
+	}
+}
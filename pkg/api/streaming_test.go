@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/pion/dtls/v2"
+)
+
+// listenDTLS accepts a single entertainment stream and sends every message it receives to a channel
+func listenDTLS(t *testing.T, clientKey string) (int, <-chan []byte) {
+	t.Helper()
+
+	psk, err := hex.DecodeString(clientKey)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	listener, err := dtls.Listen("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, &dtls.Config{
+		PSK: func([]byte) ([]byte, error) {
+			return psk, nil
+		},
+		PSKIdentityHint: []byte("hugh"),
+		CipherSuites:    []dtls.CipherSuiteID{dtls.TLS_PSK_WITH_AES_128_GCM_SHA256},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan []byte, 10)
+	go func() {
+		defer close(messages)
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			messages <- append([]byte{}, buf[:n]...)
+		}
+	}()
+
+	return listener.Addr().(*net.UDPAddr).Port, messages
+}
+
+func TestEntertainmentStreaming(t *testing.T) {
+	store := fakebridge.DefaultDatastore()
+	store.Groups["3"] = api.Group{
+		Name:   "TV area",
+		Lights: []string{"1"},
+		Type:   api.GroupTypeEntertainment,
+		Class:  "TV",
+		Stream: &api.GroupStream{ProxyMode: "auto"},
+	}
+
+	server := fakebridge.NewServer(store)
+	defer server.Close()
+	server.Bridge.PressLinkButton()
+
+	bridge := server.Endpoint(&api.API{})
+	_, err := bridge.Connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if len(bridge.ClientKey) != 32 {
+		t.Fatalf("Expected a client key but got `%s`", bridge.ClientKey)
+	}
+
+	groups, err := bridge.GetGroups()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	var entertainment, room api.Group
+	for _, group := range groups {
+		switch group.ID {
+		case "1":
+			room = group
+		case "3":
+			entertainment = group
+		}
+	}
+
+	t.Run("Test streaming to a room", func(t *testing.T) {
+		_, err := room.StartStreaming(context.Background(), api.StreamOptions{})
+		if !errors.Is(err, api.ErrNotEntertainmentGroup) {
+			t.Errorf("Expected %v but got %v", api.ErrNotEntertainmentGroup, err)
+		}
+	})
+
+	t.Run("Test streaming to an entertainment area", func(t *testing.T) {
+		port, messages := listenDTLS(t, bridge.ClientKey)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stream, err := entertainment.StartStreaming(ctx, api.StreamOptions{Port: port, Rate: 10})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if !server.Bridge.Datastore().Groups["3"].Stream.Active {
+			t.Error("Expected streaming to be active")
+		}
+
+		start := time.Now()
+		for _, color := range []api.StreamColor{api.RGBColor(1, 0xff, 0, 0), api.RGBColor(1, 0, 0xff, 0)} {
+			err := stream.Send(ctx, api.Frame{ColorSpace: api.ColorSpaceRGB, Colors: []api.StreamColor{color}})
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("Expected frames to be sent at most 10 times a second but took %v", elapsed)
+		}
+
+		for i, expected := range [][]byte{{0xff, 0xff, 0x00, 0x00, 0x00, 0x00}, {0x00, 0x00, 0xff, 0xff, 0x00, 0x00}} {
+			select {
+			case message := <-messages:
+				if !bytes.HasPrefix(message, []byte("HueStream")) || !bytes.HasSuffix(message, expected) {
+					t.Errorf("Unexpected message %d: %x", i, message)
+				}
+			case <-ctx.Done():
+				t.Fatalf("Expected message %d to be received", i)
+			}
+		}
+
+		err = stream.Close()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if server.Bridge.Datastore().Groups["3"].Stream.Active {
+			t.Error("Expected streaming to be inactive")
+		}
+	})
+}
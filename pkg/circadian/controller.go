@@ -0,0 +1,297 @@
+package circadian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// Defaults used for the zero values of a Controller's settings
+const (
+	DefaultInterval   = time.Minute
+	DefaultTransition = 10 * time.Second
+)
+
+// ErrInvalidTransition is returned by Run when adjustments would take longer than the interval between them
+// A light that's still fading when its state is read again would look like it was changed by hand.
+var ErrInvalidTransition = errors.New("Transition must not be longer than the interval")
+
+// How far a light's reported state can drift from what the controller sent before it counts as a manual change
+const (
+	brightnessTolerance  = 2
+	temperatureTolerance = 2
+)
+
+// tracked is what the controller remembers about a light it adjusted
+type tracked struct {
+	brightness  *uint8
+	temperature *uint16
+	paused      bool
+}
+
+// changed returns true if the light's state no longer matches what the controller last sent
+func (light *tracked) changed(state api.LightState) bool {
+	if light.brightness != nil && abs(int(state.Brightness)-int(*light.brightness)) > brightnessTolerance {
+		return true
+	}
+
+	if light.temperature != nil {
+		if state.ColorMode != "" && state.ColorMode != "ct" {
+			return true
+		}
+
+		if abs(int(state.Temperature)-int(*light.temperature)) > temperatureTolerance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Controller keeps lights in line with a schedule
+// Only lights that are on are adjusted (the controller never turns lights on or off). When someone changes
+// the color or brightness of a light by hand, the controller stops adjusting it until it's turned off or
+// resumed with Resume. Color temperatures are clamped to each light's TemperatureRange and lights that
+// can't change color temperature only have their brightness adjusted.
+type Controller struct {
+	Bridge   *api.Bridge
+	Schedule Schedule
+
+	// The IDs of the lights to adjust, along with every light in the groups with the given IDs
+	Lights []string
+	Groups []string
+
+	// How often lights are adjusted (DefaultInterval if zero)
+	Interval time.Duration
+
+	// How long each adjustment takes (DefaultTransition if zero)
+	// Run requires it to be at most the interval and transitions are capped at api.MaximumTransitionTime.
+	Transition time.Duration
+
+	// Called with errors encountered while running (errors are ignored if nil)
+	OnError func(error)
+
+	// Returns the current time (time.Now if nil)
+	Now func() time.Time
+
+	mu      sync.Mutex
+	tracked map[string]*tracked
+}
+
+// Paused returns true if the controller stopped adjusting a light because it was changed by hand
+func (controller *Controller) Paused(id string) bool {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	light, ok := controller.tracked[id]
+
+	return ok && light.paused
+}
+
+// Resume makes the controller adjust a paused light again
+func (controller *Controller) Resume(id string) {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	delete(controller.tracked, id)
+}
+
+// Run adjusts lights every interval until the context is done
+// ErrInvalidTransition is returned right away if the transition is longer than the interval.
+func (controller *Controller) Run(ctx context.Context) error {
+	interval := controller.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	if controller.transition() > interval {
+		return ErrInvalidTransition
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := controller.Update(ctx)
+		if err != nil && ctx.Err() == nil && controller.OnError != nil {
+			controller.OnError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Update adjusts lights to the schedule's current setting once
+// Every light is attempted even if some fail. The first failure is returned.
+func (controller *Controller) Update(ctx context.Context) error {
+	now := time.Now
+	if controller.Now != nil {
+		now = controller.Now
+	}
+	setting := controller.Schedule.At(now())
+
+	// Manual changes can only be noticed with fresh states
+	controller.Bridge.Cache.Invalidate(api.ResourceLights)
+
+	lights, err := controller.Bridge.GetLights()
+	if err != nil {
+		return err
+	}
+
+	ids, err := controller.lightIDs()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(lights, func(i, j int) bool {
+		return lights[i].ID < lights[j].ID
+	})
+
+	ctx = api.WithPriority(ctx, api.PriorityLow)
+
+	var firstErr error
+	for i := range lights {
+		if !ids[lights[i].ID] {
+			continue
+		}
+
+		err := controller.adjust(ctx, &lights[i], setting)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("Failed to adjust light `%s`: %w", lights[i].ID, err)
+		}
+	}
+
+	return firstErr
+}
+
+// lightIDs returns the IDs of every light the controller adjusts
+func (controller *Controller) lightIDs() (map[string]bool, error) {
+	ids := map[string]bool{}
+	for _, id := range controller.Lights {
+		ids[id] = true
+	}
+
+	if len(controller.Groups) == 0 {
+		return ids, nil
+	}
+
+	groups, err := controller.Bridge.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, id := range controller.Groups {
+		wanted[id] = true
+	}
+
+	for _, group := range groups {
+		if !wanted[group.ID] {
+			continue
+		}
+
+		for _, id := range group.Lights {
+			ids[id] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// adjust sends a setting to a single light unless it's off or was changed by hand
+func (controller *Controller) adjust(ctx context.Context, light *api.Light, setting Setting) error {
+	controller.mu.Lock()
+	defer controller.mu.Unlock()
+
+	if controller.tracked == nil {
+		controller.tracked = map[string]*tracked{}
+	}
+
+	// Turning a light off hands it back to the controller
+	if !light.State.On {
+		delete(controller.tracked, light.ID)
+		return nil
+	}
+
+	previous, ok := controller.tracked[light.ID]
+	if ok && (previous.paused || previous.changed(light.State)) {
+		previous.paused = true
+		return nil
+	}
+
+	update, next := controller.update(light, setting)
+	if update.Brightness == nil && update.Temperature == nil {
+		// Already there (or an on/off light)
+		controller.tracked[light.ID] = next
+		return nil
+	}
+
+	err := light.SetStateContext(ctx, update)
+	if errors.Is(err, api.ErrDeviceOff) {
+		// Turned off since its state was read
+		delete(controller.tracked, light.ID)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	controller.tracked[light.ID] = next
+
+	return nil
+}
+
+// update builds the state change bringing a light to a setting along with what to remember about the light
+// Attributes that are already at the setting are left out of the update.
+func (controller *Controller) update(light *api.Light, setting Setting) (api.LightStateUpdate, *tracked) {
+	units := api.TransitionTimeUnits(controller.transition())
+	update := api.LightStateUpdate{TransitionTime: &units}
+	next := &tracked{}
+
+	if light.Supports(api.FeatureBrightness) {
+		brightness := setting.Brightness
+		next.brightness = &brightness
+
+		if light.State.Brightness != brightness {
+			update.Brightness = &brightness
+		}
+	}
+
+	if light.Supports(api.FeatureColorTemperature) {
+		temperature := uint16(light.TemperatureRange().ClampKelvin(setting.Kelvin))
+		next.temperature = &temperature
+
+		if light.State.Temperature != temperature || light.State.ColorMode != "ct" {
+			update.Temperature = &temperature
+		}
+	}
+
+	return update, next
+}
+
+// transition returns how long each adjustment takes
+func (controller *Controller) transition() time.Duration {
+	if controller.Transition == 0 {
+		return DefaultTransition
+	}
+
+	return controller.Transition
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
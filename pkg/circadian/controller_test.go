@@ -0,0 +1,172 @@
+package circadian
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+)
+
+func TestController(t *testing.T) {
+	store := fakebridge.DefaultDatastore()
+	for _, id := range []string{"1", "2", "3"} {
+		light := store.Lights[id]
+		light.State.On = true
+		store.Lights[id] = light
+	}
+
+	server := fakebridge.NewServer(store)
+	defer server.Close()
+	server.Bridge.PressLinkButton()
+
+	bridge := server.Endpoint(&api.API{})
+	_, err := bridge.Connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	greenwich := time.FixedZone("GMT", 0)
+	now := time.Date(2021, 3, 20, 2, 0, 0, 0, greenwich)
+
+	controller := &Controller{
+		Bridge:   bridge,
+		Schedule: Schedule{Latitude: 51.5074, Longitude: -0.1278, NightKelvin: 2000, NightBrightness: 80},
+		Groups:   []string{"2"},
+		Now:      func() time.Time { return now },
+	}
+
+	state := func(id string) api.LightState {
+		return server.Bridge.Datastore().Lights[id].State
+	}
+
+	setState := func(id string, update api.LightStateUpdate) {
+		t.Helper()
+
+		lights, err := bridge.GetLights()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		for _, light := range lights {
+			if light.ID == id {
+				err := light.SetState(update)
+				if err != nil {
+					t.Fatalf("Expected no error but got %v", err)
+				}
+			}
+		}
+	}
+
+	update := func() {
+		t.Helper()
+
+		err := controller.Update(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+
+	t.Run("Test lights are adjusted within their ranges", func(t *testing.T) {
+		update()
+
+		// 2000K is warmer than the lights support (454 mireds is about 2200K)
+		for _, id := range []string{"1", "2"} {
+			if state(id).Temperature != 454 || state(id).Brightness != 80 {
+				t.Errorf("Expected light %s to be at 454 mireds and 80 brightness but got %+v", id, state(id))
+			}
+		}
+
+		if state("3").Brightness != 80 || state("3").Temperature != 0 {
+			t.Errorf("Expected light 3 to only be dimmed to 80 but got %+v", state("3"))
+		}
+	})
+
+	t.Run("Test manual changes pause adjustments", func(t *testing.T) {
+		brightness := uint8(200)
+		setState("1", api.LightStateUpdate{Brightness: &brightness})
+
+		now = now.Add(10 * time.Hour)
+		update()
+
+		if !controller.Paused("1") || controller.Paused("2") {
+			t.Errorf("Expected only light 1 to be paused")
+		}
+
+		if state("1").Brightness != 200 {
+			t.Errorf("Expected light 1 to be left alone but got %+v", state("1"))
+		}
+
+		if state("2").Brightness <= 80 || state("2").Temperature >= 454 {
+			t.Errorf("Expected light 2 to brighten and cool down but got %+v", state("2"))
+		}
+	})
+
+	t.Run("Test turning a light off resumes adjustments", func(t *testing.T) {
+		off, on := false, true
+		setState("1", api.LightStateUpdate{On: &off})
+		update()
+
+		if controller.Paused("1") {
+			t.Errorf("Expected light 1 to no longer be paused")
+		}
+
+		if state("1").On || state("1").Brightness != 200 {
+			t.Errorf("Expected light 1 to be left off but got %+v", state("1"))
+		}
+
+		setState("1", api.LightStateUpdate{On: &on})
+		update()
+
+		if state("1").Temperature != state("2").Temperature || state("1").Brightness != state("2").Brightness {
+			t.Errorf("Expected light 1 to match light 2 but got %+v and %+v", state("1"), state("2"))
+		}
+	})
+}
+
+func TestControllerTransition(t *testing.T) {
+	store := fakebridge.DefaultDatastore()
+	light := store.Lights["2"]
+	light.State.On = true
+	store.Lights["2"] = light
+
+	server := fakebridge.NewServer(store)
+	defer server.Close()
+	server.Bridge.PressLinkButton()
+
+	bridge := server.Endpoint(&api.API{})
+	_, err := bridge.Connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	t.Run("Test transitions longer than the interval are rejected", func(t *testing.T) {
+		controller := &Controller{Bridge: bridge, Lights: []string{"2"}, Interval: time.Second}
+
+		err := controller.Run(context.Background())
+		if err != ErrInvalidTransition {
+			t.Errorf("Expected %v but got %v", ErrInvalidTransition, err)
+		}
+	})
+
+	t.Run("Test long transitions are capped", func(t *testing.T) {
+		controller := &Controller{Bridge: bridge, Lights: []string{"2"}, Transition: 3 * time.Hour}
+
+		lights, err := bridge.GetLights()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		for _, light := range lights {
+			if light.ID != "2" {
+				continue
+			}
+
+			update, _ := controller.update(&light, Setting{Kelvin: 2700, Brightness: 100})
+			if update.TransitionTime == nil || *update.TransitionTime != 65535 {
+				t.Errorf("Expected a transition time of 65535 but got %v", update.TransitionTime)
+			}
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package circadian
+
+import (
+	"math"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// Defaults used for the zero values of a Schedule's settings
+const (
+	DefaultNightKelvin     = 2200
+	DefaultDayKelvin       = 5500
+	DefaultNightBrightness = 100
+	DefaultDayBrightness   = api.MaximumBrightness
+)
+
+// Setting is the color temperature and brightness lights should have at a certain time
+type Setting struct {
+	Kelvin     uint
+	Brightness uint8
+}
+
+// Schedule maps the time of day to a setting based on the position of the sun
+// Lights are at their night setting from sunset to sunrise and follow the arc of the sun during the day,
+// peaking at their day setting at solar noon. Near the poles the peak is at solar noon during polar days
+// and lights stay at their night setting during polar nights.
+type Schedule struct {
+	Latitude  float64
+	Longitude float64
+
+	// Color temperatures in Kelvin (DefaultNightKelvin and DefaultDayKelvin if zero)
+	NightKelvin uint
+	DayKelvin   uint
+
+	// Brightnesses (DefaultNightBrightness and DefaultDayBrightness if zero)
+	NightBrightness uint8
+	DayBrightness   uint8
+}
+
+// withDefaults fills in any unset settings
+func (schedule Schedule) withDefaults() Schedule {
+	if schedule.NightKelvin == 0 {
+		schedule.NightKelvin = DefaultNightKelvin
+	}
+
+	if schedule.DayKelvin == 0 {
+		schedule.DayKelvin = DefaultDayKelvin
+	}
+
+	if schedule.NightBrightness == 0 {
+		schedule.NightBrightness = DefaultNightBrightness
+	}
+
+	if schedule.DayBrightness == 0 {
+		schedule.DayBrightness = DefaultDayBrightness
+	}
+
+	return schedule
+}
+
+// daylight returns how far through the day the sun is at a time, from 0 (night) to 1 (solar noon)
+func (schedule Schedule) daylight(t time.Time) float64 {
+	day := SunTimes(t, schedule.Latitude, schedule.Longitude)
+
+	switch {
+	case day.PolarNight:
+		return 0
+	case day.PolarDay:
+		// Peak at noon and bottom out at midnight
+		offset := t.Sub(day.Noon).Hours() / 24
+		return (1 + math.Cos(2*math.Pi*offset)) / 2
+	case t.Before(day.Sunrise) || !t.Before(day.Sunset):
+		return 0
+	}
+
+	progress := float64(t.Sub(day.Sunrise)) / float64(day.Sunset.Sub(day.Sunrise))
+
+	return math.Sin(math.Pi * progress)
+}
+
+// At returns the setting lights should have at a time
+func (schedule Schedule) At(t time.Time) Setting {
+	schedule = schedule.withDefaults()
+	daylight := schedule.daylight(t)
+
+	interpolate := func(night, day float64) float64 {
+		return math.Round(night + (day-night)*daylight)
+	}
+
+	return Setting{
+		Kelvin:     uint(interpolate(float64(schedule.NightKelvin), float64(schedule.DayKelvin))),
+		Brightness: uint8(interpolate(float64(schedule.NightBrightness), float64(schedule.DayBrightness))),
+	}
+}
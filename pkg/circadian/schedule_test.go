@@ -0,0 +1,73 @@
+package circadian
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleAt(t *testing.T) {
+	greenwich := time.FixedZone("GMT", 0)
+	london := Schedule{Latitude: 51.5074, Longitude: -0.1278}
+	day := SunTimes(time.Date(2021, 3, 20, 12, 0, 0, 0, greenwich), london.Latitude, london.Longitude)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		time     time.Time
+		expected Setting
+	}{
+		{
+			name:     "Test night",
+			schedule: london,
+			time:     time.Date(2021, 3, 20, 2, 0, 0, 0, greenwich),
+			expected: Setting{Kelvin: DefaultNightKelvin, Brightness: DefaultNightBrightness},
+		},
+		{
+			name:     "Test sunrise",
+			schedule: london,
+			time:     day.Sunrise,
+			expected: Setting{Kelvin: DefaultNightKelvin, Brightness: DefaultNightBrightness},
+		},
+		{
+			name:     "Test solar noon",
+			schedule: london,
+			time:     day.Sunrise.Add(day.Sunset.Sub(day.Sunrise) / 2),
+			expected: Setting{Kelvin: DefaultDayKelvin, Brightness: DefaultDayBrightness},
+		},
+		{
+			name: "Test custom settings",
+			schedule: Schedule{
+				Latitude:        london.Latitude,
+				Longitude:       london.Longitude,
+				NightKelvin:     2000,
+				DayKelvin:       4000,
+				NightBrightness: 50,
+				DayBrightness:   150,
+			},
+			time:     day.Sunrise.Add(day.Sunset.Sub(day.Sunrise) / 6),
+			expected: Setting{Kelvin: 3000, Brightness: 100},
+		},
+		{
+			name:     "Test after sunset",
+			schedule: london,
+			time:     day.Sunset.Add(time.Minute),
+			expected: Setting{Kelvin: DefaultNightKelvin, Brightness: DefaultNightBrightness},
+		},
+		{
+			name:     "Test polar night",
+			schedule: Schedule{Latitude: 69.6492, Longitude: 18.9553},
+			time:     time.Date(2021, 12, 21, 12, 0, 0, 0, greenwich),
+			expected: Setting{Kelvin: DefaultNightKelvin, Brightness: DefaultNightBrightness},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := tt.schedule.At(tt.time)
+
+			if setting != tt.expected {
+				t.Errorf("Expected %+v but got %+v", tt.expected, setting)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package circadian adjusts lights to follow the sun
+// The position of the sun is calculated locally from a latitude and longitude. A Schedule maps the time of
+// day to a color temperature and brightness (cool and bright around noon, warm and dim at night) and a
+// Controller keeps lights in line with it, leaving alone any light someone changed by hand.
+package circadian
+
+import (
+	"math"
+	"time"
+)
+
+// Constants used by the sunrise equation (see https://en.wikipedia.org/wiki/Sunrise_equation)
+const (
+	julianUnixEpoch = 2440587.5 // Julian date of 1970-01-01T00:00:00Z
+	julianJ2000     = 2451545.0 // Julian date of 2000-01-01T12:00:00Z
+
+	earthTilt = 23.4397 // Degrees
+
+	// The sun is considered up when its upper edge clears the horizon (accounting for refraction)
+	sunriseElevation = -0.833 // Degrees
+)
+
+// Day holds the times the sun rises, peaks and sets on a certain day at a certain place
+type Day struct {
+	Sunrise time.Time
+	Noon    time.Time
+	Sunset  time.Time
+
+	// Set when the sun doesn't rise or set that day (near the poles). Sunrise and Sunset are zero.
+	PolarDay   bool // The sun never sets
+	PolarNight bool // The sun never rises
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+func julianDate(t time.Time) float64 {
+	return float64(t.Unix())/86400 + julianUnixEpoch
+}
+
+func fromJulianDate(date float64, location *time.Location) time.Time {
+	seconds := math.Round((date - julianUnixEpoch) * 86400)
+
+	return time.Unix(int64(seconds), 0).In(location)
+}
+
+// SunTimes calculates when the sun rises and sets on the day of the given time (in its location)
+// Latitude is positive north of the equator and longitude is positive east of Greenwich. Times are accurate
+// to within a couple of minutes, which is plenty for lighting.
+func SunTimes(date time.Time, latitude, longitude float64) Day {
+	location := date.Location()
+	localNoon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, location)
+
+	// Mean solar noon at the longitude on the Julian day closest to local noon
+	day := math.Round(julianDate(localNoon) - julianJ2000 + longitude/360)
+	meanNoon := day - longitude/360
+
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*math.Sin(radians(anomaly)) + 0.02*math.Sin(radians(2*anomaly)) +
+		0.0003*math.Sin(radians(3*anomaly))
+	eclipticLongitude := math.Mod(anomaly+center+180+102.9372, 360)
+
+	transit := julianJ2000 + meanNoon + 0.0053*math.Sin(radians(anomaly)) -
+		0.0069*math.Sin(radians(2*eclipticLongitude))
+
+	declination := math.Asin(math.Sin(radians(eclipticLongitude)) * math.Sin(radians(earthTilt)))
+
+	cosHourAngle := (math.Sin(radians(sunriseElevation)) - math.Sin(radians(latitude))*math.Sin(declination)) /
+		(math.Cos(radians(latitude)) * math.Cos(declination))
+
+	result := Day{Noon: fromJulianDate(transit, location)}
+
+	switch {
+	case cosHourAngle < -1:
+		result.PolarDay = true
+	case cosHourAngle > 1:
+		result.PolarNight = true
+	default:
+		hourAngle := degrees(math.Acos(cosHourAngle))
+		result.Sunrise = fromJulianDate(transit-hourAngle/360, location)
+		result.Sunset = fromJulianDate(transit+hourAngle/360, location)
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+package circadian
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	pacific := time.FixedZone("PDT", -7*60*60)
+	greenwich := time.FixedZone("GMT", 0)
+	sydney := time.FixedZone("AEDT", 11*60*60)
+	norway := time.FixedZone("CET", 60*60)
+
+	tests := []struct {
+		name       string
+		date       time.Time
+		latitude   float64
+		longitude  float64
+		sunrise    time.Time
+		sunset     time.Time
+		polarDay   bool
+		polarNight bool
+	}{
+		{
+			name:      "Test San Francisco summer solstice",
+			date:      time.Date(2021, 6, 21, 3, 0, 0, 0, pacific),
+			latitude:  37.7749,
+			longitude: -122.4194,
+			sunrise:   time.Date(2021, 6, 21, 5, 48, 0, 0, pacific),
+			sunset:    time.Date(2021, 6, 21, 20, 35, 0, 0, pacific),
+		},
+		{
+			name:      "Test London winter solstice",
+			date:      time.Date(2021, 12, 21, 23, 0, 0, 0, greenwich),
+			latitude:  51.5074,
+			longitude: -0.1278,
+			sunrise:   time.Date(2021, 12, 21, 8, 4, 0, 0, greenwich),
+			sunset:    time.Date(2021, 12, 21, 15, 53, 0, 0, greenwich),
+		},
+		{
+			name:      "Test Sydney",
+			date:      time.Date(2021, 1, 1, 0, 0, 0, 0, sydney),
+			latitude:  -33.8688,
+			longitude: 151.2093,
+			sunrise:   time.Date(2021, 1, 1, 5, 47, 0, 0, sydney),
+			sunset:    time.Date(2021, 1, 1, 20, 9, 0, 0, sydney),
+		},
+		{
+			name:       "Test Tromsø polar night",
+			date:       time.Date(2021, 12, 21, 12, 0, 0, 0, norway),
+			latitude:   69.6492,
+			longitude:  18.9553,
+			polarNight: true,
+		},
+		{
+			name:      "Test Tromsø midnight sun",
+			date:      time.Date(2021, 6, 21, 12, 0, 0, 0, norway),
+			latitude:  69.6492,
+			longitude: 18.9553,
+			polarDay:  true,
+		},
+	}
+
+	within := func(actual, expected time.Time) bool {
+		difference := actual.Sub(expected)
+		return difference > -2*time.Minute && difference < 2*time.Minute
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := SunTimes(tt.date, tt.latitude, tt.longitude)
+
+			if day.PolarDay != tt.polarDay || day.PolarNight != tt.polarNight {
+				t.Fatalf("Expected polar day %v and polar night %v but got %v and %v",
+					tt.polarDay, tt.polarNight, day.PolarDay, day.PolarNight)
+			}
+
+			if !within(day.Sunrise, tt.sunrise) {
+				t.Errorf("Expected sunrise around %v but got %v", tt.sunrise, day.Sunrise)
+			}
+
+			if !within(day.Sunset, tt.sunset) {
+				t.Errorf("Expected sunset around %v but got %v", tt.sunset, day.Sunset)
+			}
+
+			if day.Noon.Day() != tt.date.Day() {
+				t.Errorf("Expected solar noon on %v but got %v", tt.date, day.Noon)
+			}
+		})
+	}
+}
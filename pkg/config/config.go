@@ -0,0 +1,233 @@
+// Package config loads the configuration shared by hugh's commands
+// The configuration is a YAML file (config.yaml in the hugh directory under the XDG config directory) with
+// named profiles describing a bridge and how to talk to it:
+//
+//	default_profile: home
+//	profiles:
+//	  home:
+//	    bridge: 192.168.1.2
+//	    username: 1234567890abcdef
+//	    clientkey: 0123456789ABCDEF0123456789ABCDEF
+//	    timeout: 5s
+//	    output: table
+//	    rate_limits:
+//	      lights: {per_second: 10, burst: 1}
+//	      groups: {per_second: 1, burst: 1}
+//	    aliases:
+//	      kitchen: ["Kitchen 1", "Kitchen 2"]
+//	      downstairs: ["Kitchen*", "Hallway"]
+//
+// Commands apply settings in order of precedence: flags, then environment variables (see the Env
+// constants), then the profile, then the defaults.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables that override the configuration file
+const (
+	EnvConfig   = "HUGH_CONFIG"   // Path of the configuration file
+	EnvProfile  = "HUGH_PROFILE"  // Profile to use instead of the default one
+	EnvBridge   = "HUGH_BRIDGE"   // Address or ID of the bridge
+	EnvUsername = "HUGH_USERNAME" // Username to send requests as
+	EnvTimeout  = "HUGH_TIMEOUT"  // How long to wait for requests and discovery (a duration like 5s)
+	EnvOutput   = "HUGH_OUTPUT"   // Output format of listings
+)
+
+// DefaultTimeout is how long requests and discovery wait when a profile doesn't set a timeout
+const DefaultTimeout = 10 * time.Second
+
+// ErrUnknownProfile is returned when a profile isn't in the configuration
+var ErrUnknownProfile = errors.New("Unknown profile")
+
+// Config represents the configuration file
+type Config struct {
+	// The profile used when one isn't chosen (the only profile is used if this is empty)
+	DefaultProfile string `yaml:"default_profile"`
+
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile describes a bridge and how to talk to it
+// Zero values mean the setting isn't configured.
+type Profile struct {
+	Bridge     string              `yaml:"bridge"` // Address (host[:port]) or ID of the bridge
+	Username   string              `yaml:"username"`
+	ClientKey  string              `yaml:"clientkey"`
+	Timeout    time.Duration       `yaml:"timeout"`
+	Output     string              `yaml:"output"`
+	RateLimits RateLimits          `yaml:"rate_limits"`
+	Aliases    map[string][]string `yaml:"aliases"` // Names for groups of lights (by name, pattern or ID)
+}
+
+// RateLimits describes the budgets requests to the bridge are scheduled with (see api.RateLimiter)
+type RateLimits struct {
+	Lights *RateLimit `yaml:"lights"` // api.DefaultLightRateLimit if nil
+	Groups *RateLimit `yaml:"groups"` // api.DefaultGroupRateLimit if nil
+}
+
+// RateLimit describes a token bucket (see api.RateLimit)
+type RateLimit struct {
+	PerSecond float64 `yaml:"per_second"`
+	Burst     int     `yaml:"burst"`
+}
+
+// Path returns where the configuration file is read from
+// The HUGH_CONFIG environment variable takes precedence over the XDG config directory.
+func Path() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "hugh", "config.yaml"), nil
+}
+
+// Load reads a configuration file
+// Unknown settings are reported as errors so typos aren't silently ignored.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(config)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Invalid configuration file `%s`: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// LoadDefault reads the configuration file at Path
+// An empty configuration is returned if the file doesn't exist.
+func LoadDefault() (*Config, string, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, "", nil
+	}
+
+	config, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, path, nil
+	}
+
+	return config, path, err
+}
+
+// ProfileName returns the name of the profile chosen by a name
+// An empty name chooses the HUGH_PROFILE environment variable's profile, then the default profile, then
+// the only profile. An empty name is returned if none of those apply.
+func (config *Config) ProfileName(name string) string {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+
+	if name == "" {
+		name = config.DefaultProfile
+	}
+
+	if name == "" && len(config.Profiles) == 1 {
+		for only := range config.Profiles {
+			name = only
+		}
+	}
+
+	return name
+}
+
+// Profile returns the profile chosen by a name (see ProfileName)
+// An empty profile is returned if no profile is chosen.
+func (config *Config) Profile(name string) (Profile, error) {
+	name = config.ProfileName(name)
+	if name == "" {
+		return Profile{}, nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w `%s` (known profiles: %v)", ErrUnknownProfile, name, config.profileNames())
+	}
+
+	return profile, nil
+}
+
+func (config *Config) profileNames() []string {
+	names := []string{}
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// RateLimiter creates a rate limiter with the profile's budgets
+func (profile Profile) RateLimiter() *api.RateLimiter {
+	lights, groups := api.DefaultLightRateLimit, api.DefaultGroupRateLimit
+	if profile.RateLimits.Lights != nil {
+		lights = api.RateLimit(*profile.RateLimits.Lights)
+	}
+
+	if profile.RateLimits.Groups != nil {
+		groups = api.RateLimit(*profile.RateLimits.Groups)
+	}
+
+	return api.NewRateLimiter(lights, groups)
+}
+
+// API creates an API with the profile's timeout and rate limits that retries transient failures
+func (profile Profile) API() *api.API {
+	timeout := profile.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	// Each API gets its own copy so changing its policy doesn't change the default
+	retry := api.DefaultRetryPolicy
+
+	return &api.API{
+		Client:         http.Client{Timeout: timeout},
+		TimeoutSeconds: int(math.Ceil(timeout.Seconds())),
+		RateLimiter:    profile.RateLimiter(),
+		Retry:          &retry,
+	}
+}
+
+// ExpandAliases replaces the aliases in a list of light names with the names they stand for
+// Names that aren't aliases are returned unchanged. Aliases aren't expanded recursively.
+func (profile Profile) ExpandAliases(names []string) []string {
+	expanded := []string{}
+	for _, name := range names {
+		if lights, ok := profile.Aliases[name]; ok {
+			expanded = append(expanded, lights...)
+			continue
+		}
+
+		expanded = append(expanded, name)
+	}
+
+	return expanded
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(path, []byte(contents), 0600)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected *Config
+		invalid  bool
+	}{
+		{
+			name: "Test profiles",
+			contents: `default_profile: home
+profiles:
+  home:
+    bridge: 192.168.1.2:8080
+    username: abc
+    clientkey: def
+    timeout: 1m30s
+    output: json
+    rate_limits:
+      lights: {per_second: 5, burst: 2}
+    aliases:
+      kitchen: ["Kitchen 1", "Kitchen 2"]
+  office:
+    bridge: 001788fffe000000
+`,
+			expected: &Config{
+				DefaultProfile: "home",
+				Profiles: map[string]Profile{
+					"home": {
+						Bridge:     "192.168.1.2:8080",
+						Username:   "abc",
+						ClientKey:  "def",
+						Timeout:    90 * time.Second,
+						Output:     "json",
+						RateLimits: RateLimits{Lights: &RateLimit{PerSecond: 5, Burst: 2}},
+						Aliases:    map[string][]string{"kitchen": {"Kitchen 1", "Kitchen 2"}},
+					},
+					"office": {Bridge: "001788fffe000000"},
+				},
+			},
+		},
+		{name: "Test empty file", contents: "", expected: &Config{}},
+		{name: "Test unknown setting", contents: "profiles:\n  home:\n    adress: 192.168.1.2\n", invalid: true},
+		{name: "Test invalid timeout", contents: "profiles:\n  home:\n    timeout: soon\n", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.contents)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			config, err := Load(path)
+			if tt.invalid {
+				if err == nil {
+					t.Errorf("Expected an error but got %+v", config)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, config); diff != "" {
+				t.Errorf("Unexpected configuration (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	path := writeConfig(t, "profiles:\n  home:\n    username: abc\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Setenv(EnvConfig, path)
+	defer os.Unsetenv(EnvConfig)
+
+	config, loaded, err := LoadDefault()
+	if err != nil || loaded != path || config.Profiles["home"].Username != "abc" {
+		t.Errorf("Expected the configuration at %s but got %+v from %s (%v)", path, config, loaded, err)
+	}
+
+	missing := filepath.Join(filepath.Dir(path), "missing.yaml")
+	os.Setenv(EnvConfig, missing)
+
+	config, loaded, err = LoadDefault()
+	if err != nil || loaded != missing || len(config.Profiles) != 0 {
+		t.Errorf("Expected an empty configuration but got %+v from %s (%v)", config, loaded, err)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	home := Profile{Username: "home"}
+	office := Profile{Username: "office"}
+
+	tests := []struct {
+		name     string
+		config   Config
+		profile  string
+		env      string
+		expected Profile
+		err      error
+	}{
+		{name: "Test named profile", config: Config{DefaultProfile: "home", Profiles: map[string]Profile{"home": home, "office": office}}, profile: "office", env: "home", expected: office},
+		{name: "Test profile from environment", config: Config{DefaultProfile: "home", Profiles: map[string]Profile{"home": home, "office": office}}, env: "office", expected: office},
+		{name: "Test default profile", config: Config{DefaultProfile: "home", Profiles: map[string]Profile{"home": home, "office": office}}, expected: home},
+		{name: "Test only profile", config: Config{Profiles: map[string]Profile{"office": office}}, expected: office},
+		{name: "Test no profile chosen", config: Config{Profiles: map[string]Profile{"home": home, "office": office}}, expected: Profile{}},
+		{name: "Test no profiles", config: Config{}, expected: Profile{}},
+		{name: "Test unknown profile", config: Config{Profiles: map[string]Profile{"home": home}}, profile: "cabin", err: ErrUnknownProfile},
+		{name: "Test unknown default profile", config: Config{DefaultProfile: "cabin"}, err: ErrUnknownProfile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(EnvProfile, tt.env)
+			defer os.Unsetenv(EnvProfile)
+
+			profile, err := tt.config.Profile(tt.profile)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v but got %v", tt.err, err)
+			}
+
+			if diff := cmp.Diff(tt.expected, profile); diff != "" {
+				t.Errorf("Unexpected profile (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAPI(t *testing.T) {
+	hue := Profile{}.API()
+	if hue.Client.Timeout != DefaultTimeout || hue.TimeoutSeconds != 10 || hue.RateLimiter == nil || hue.Retry == nil {
+		t.Errorf("Expected the defaults but got %+v", hue)
+	}
+
+	hue = Profile{Timeout: 1500 * time.Millisecond}.API()
+	if hue.Client.Timeout != 1500*time.Millisecond || hue.TimeoutSeconds != 2 {
+		t.Errorf("Expected the profile's timeout but got %+v", hue)
+	}
+
+	hue.Retry.MaxAttempts = 10
+	if api.DefaultRetryPolicy.MaxAttempts == 10 || (Profile{}).API().Retry.MaxAttempts == 10 {
+		t.Errorf("Expected changes to an API's retry policy to leave the default alone")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	// Only one light request can be sent at once by default
+	limiter := Profile{}.RateLimiter()
+	if sent := burst(limiter, api.ClassLight); sent != api.DefaultLightRateLimit.Burst {
+		t.Errorf("Expected %d requests to be sent at once but got %d", api.DefaultLightRateLimit.Burst, sent)
+	}
+
+	limiter = Profile{RateLimits: RateLimits{Lights: &RateLimit{PerSecond: 1, Burst: 3}}}.RateLimiter()
+	if sent := burst(limiter, api.ClassLight); sent != 3 {
+		t.Errorf("Expected 3 requests to be sent at once but got %d", sent)
+	}
+
+	if sent := burst(limiter, api.ClassGroup); sent != api.DefaultGroupRateLimit.Burst {
+		t.Errorf("Expected %d group requests to be sent at once but got %d", api.DefaultGroupRateLimit.Burst, sent)
+	}
+}
+
+// burst counts the requests a rate limiter lets through without waiting
+func burst(limiter *api.RateLimiter, class api.RequestClass) int {
+	sent := 0
+	for ; sent < 10; sent++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err := limiter.Wait(ctx, class, api.PriorityNormal)
+		cancel()
+
+		if err != nil {
+			break
+		}
+	}
+
+	return sent
+}
+
+func TestExpandAliases(t *testing.T) {
+	profile := Profile{Aliases: map[string][]string{
+		"kitchen":    {"Kitchen 1", "Kitchen 2"},
+		"downstairs": {"kitchen", "Hallway"},
+	}}
+
+	expected := []string{"Kitchen 1", "Kitchen 2", "3", "kitchen", "Hallway"}
+	got := profile.ExpandAliases([]string{"kitchen", "3", "downstairs"})
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("Unexpected lights (-want +got):\n%s", diff)
+	}
+}
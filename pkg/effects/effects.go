@@ -0,0 +1,324 @@
+// Package effects animates lights and groups from the client
+// Effects send a series of state changes to a target over time. They stop as soon as their context is
+// done and go through the target bridge's rate limiter like any other request, so effects on many lights
+// at once slow down rather than flood the bridge. Give effects a low priority (see api.WithPriority) to
+// keep interactive changes responsive while they run.
+package effects
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// Target is something whose state an effect changes (an *api.Light or *api.Group)
+type Target interface {
+	SetStateContext(ctx context.Context, update api.LightStateUpdate) error
+}
+
+// Effect changes a target's state over time
+type Effect interface {
+	// Run plays the effect on a target, returning once it's done or the context is done
+	Run(ctx context.Context, target Target) error
+}
+
+// EffectFunc adapts a function to an Effect
+type EffectFunc func(ctx context.Context, target Target) error
+
+// Run calls the function
+func (f EffectFunc) Run(ctx context.Context, target Target) error {
+	return f(ctx, target)
+}
+
+// MaximumStrobeRate is the most flashes per second a strobe is allowed
+// Flashing more than three times a second can trigger seizures in people with photosensitive epilepsy.
+const MaximumStrobeRate = 2.0
+
+// sleep waits for a duration or until the context is done (replaced in tests)
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// transitionTime converts a duration to the bridge's transition time units (see api.TransitionTimeUnits)
+func transitionTime(d time.Duration) *uint16 {
+	units := api.TransitionTimeUnits(d)
+	return &units
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func uint8Ptr(value uint8) *uint8 {
+	return &value
+}
+
+// set sends a state change that transitions over a duration then waits for the transition to finish
+func set(ctx context.Context, target Target, update api.LightStateUpdate, duration time.Duration) error {
+	update.TransitionTime = transitionTime(duration)
+
+	err := target.SetStateContext(ctx, update)
+	if err != nil {
+		return err
+	}
+
+	return sleep(ctx, duration)
+}
+
+// Fade transitions a target to a state over a duration
+// The bridge interpolates between the current and the new state so only a single request is sent.
+func Fade(to api.LightStateUpdate, duration time.Duration) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		return set(ctx, target, to, duration)
+	})
+}
+
+// Wait does nothing for a duration (useful in sequences)
+func Wait(duration time.Duration) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		return sleep(ctx, duration)
+	})
+}
+
+// Pulse breathes a target between two brightnesses, taking period for each breath
+// The target is turned on first. Pulses repeat count times (or until the context is done if count is 0).
+func Pulse(low, high uint8, period time.Duration, count int) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		err := target.SetStateContext(ctx, api.LightStateUpdate{On: boolPtr(true)})
+		if err != nil {
+			return err
+		}
+
+		for i := 0; count == 0 || i < count; i++ {
+			err := set(ctx, target, api.LightStateUpdate{Brightness: uint8Ptr(high)}, period/2)
+			if err != nil {
+				return err
+			}
+
+			err = set(ctx, target, api.LightStateUpdate{Brightness: uint8Ptr(low)}, period/2)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Strobe flashes a target on and off rate times a second for a duration
+// The rate is capped at MaximumStrobeRate. The target is left on.
+func Strobe(rate float64, duration time.Duration) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		rate := math.Min(rate, MaximumStrobeRate)
+		if rate <= 0 {
+			return nil
+		}
+
+		half := time.Duration(float64(time.Second) / rate / 2)
+		flashes := int(duration.Seconds() * rate)
+
+		for i := 0; i < flashes; i++ {
+			for _, on := range []bool{false, true} {
+				err := set(ctx, target, api.LightStateUpdate{On: boolPtr(on)}, 0)
+				if err != nil {
+					return err
+				}
+
+				err = sleep(ctx, half)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// candleTemperature is the color temperature of a candle flame (about 1900K)
+const candleTemperature = 500
+
+// CandleOptions configures a candle flicker
+type CandleOptions struct {
+	// Average brightness of the flame
+	Brightness uint8
+
+	// How far the brightness strays from the average
+	Variation uint8
+
+	// Source of randomness (a time seeded source if nil)
+	Rand *rand.Rand
+}
+
+// Candle flickers a target like a candle flame for a duration (or until the context is done if zero)
+// Color temperature lights are set to a warm white first (the warmest white they support).
+func Candle(options CandleOptions, duration time.Duration) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		random := options.Rand
+		if random == nil {
+			random = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+
+		parent := ctx
+		if duration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, duration)
+			defer cancel()
+		}
+
+		err := target.SetStateContext(ctx, api.LightStateUpdate{On: boolPtr(true)})
+		if err != nil {
+			return err
+		}
+
+		// Lights without color temperatures just flicker
+		temperature := uint16(candleTemperature)
+		if light, ok := target.(*api.Light); ok {
+			temperature = uint16(light.TemperatureRange().Clamp(candleTemperature))
+		}
+
+		err = target.SetStateContext(ctx, api.LightStateUpdate{Temperature: &temperature})
+		if err != nil && !errors.Is(err, api.ErrUnsupportedFeature) && !errors.Is(err, api.ErrParameterNotAvailable) {
+			return err
+		}
+
+		for {
+			offset := 0
+			if options.Variation > 0 {
+				offset = random.Intn(2*int(options.Variation)+1) - int(options.Variation)
+			}
+			brightness := clampBrightness(int(options.Brightness) + offset)
+			step := time.Duration(100+random.Intn(200)) * time.Millisecond
+
+			err := set(ctx, target, api.LightStateUpdate{Brightness: uint8Ptr(brightness)}, step)
+			if err != nil {
+				// Running out of time isn't an error
+				if parent.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+					return nil
+				}
+
+				return err
+			}
+		}
+	})
+}
+
+// ColorCycle moves a target through a palette of colors, spending step on each
+// The palette is played loops times (or until the context is done if loops is 0).
+func ColorCycle(palette []api.CIECoord, step time.Duration, loops int) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		if len(palette) == 0 {
+			return nil
+		}
+
+		err := target.SetStateContext(ctx, api.LightStateUpdate{On: boolPtr(true)})
+		if err != nil {
+			return err
+		}
+
+		for i := 0; loops == 0 || i < loops; i++ {
+			for _, color := range palette {
+				color := color
+				err := set(ctx, target, api.LightStateUpdate{CIECoords: &color}, step)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Sequence plays effects one after the other on the same target
+func Sequence(effects ...Effect) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		for _, effect := range effects {
+			err := effect.Run(ctx, target)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Repeat plays an effect count times
+func Repeat(effect Effect, count int) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		for i := 0; i < count; i++ {
+			err := effect.Run(ctx, target)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// On binds an effect to a target so it ignores the target it's run on
+// This is mostly useful with Parallel to animate several lights differently at once.
+func On(target Target, effect Effect) Effect {
+	return EffectFunc(func(ctx context.Context, _ Target) error {
+		return effect.Run(ctx, target)
+	})
+}
+
+// Parallel plays effects at the same time and waits for all of them to finish
+// If one of them fails the others are stopped and the first error is returned.
+func Parallel(effects ...Effect) Effect {
+	return EffectFunc(func(ctx context.Context, target Target) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		var once sync.Once
+		var firstErr error
+
+		for _, effect := range effects {
+			wg.Add(1)
+			go func(effect Effect) {
+				defer wg.Done()
+
+				err := effect.Run(ctx, target)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}(effect)
+		}
+
+		wg.Wait()
+
+		return firstErr
+	})
+}
+
+func clampBrightness(brightness int) uint8 {
+	if brightness < api.MinimumBrightness {
+		return api.MinimumBrightness
+	}
+
+	if brightness > api.MaximumBrightness {
+		return api.MaximumBrightness
+	}
+
+	return uint8(brightness)
+}
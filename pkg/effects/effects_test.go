@@ -0,0 +1,267 @@
+package effects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordingTarget remembers every state change sent to it
+type recordingTarget struct {
+	mu      sync.Mutex
+	updates []api.LightStateUpdate
+
+	// Called with every update before it's recorded
+	hook func(update api.LightStateUpdate) error
+}
+
+func (target *recordingTarget) SetStateContext(ctx context.Context, update api.LightStateUpdate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if target.hook != nil {
+		if err := target.hook(update); err != nil {
+			return err
+		}
+	}
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+
+	target.updates = append(target.updates, update)
+
+	return nil
+}
+
+// fakeSleep replaces sleep with a function recording the durations waited for
+func fakeSleep(t *testing.T) *[]time.Duration {
+	var mu sync.Mutex
+	waits := []time.Duration{}
+
+	original := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = original })
+
+	return &waits
+}
+
+func transition(units uint16) *uint16 {
+	return &units
+}
+
+func TestEffects(t *testing.T) {
+	xy := func(x, y float64) *api.CIECoord { return &api.CIECoord{x, y} }
+
+	tests := []struct {
+		name     string
+		effect   Effect
+		expected []api.LightStateUpdate
+		waits    []time.Duration
+	}{
+		{
+			name:   "Test fade",
+			effect: Fade(api.LightStateUpdate{Brightness: uint8Ptr(10)}, 3*time.Second),
+			expected: []api.LightStateUpdate{
+				{Brightness: uint8Ptr(10), TransitionTime: transition(30)},
+			},
+			waits: []time.Duration{3 * time.Second},
+		},
+		{
+			name:   "Test pulse",
+			effect: Pulse(10, 200, 2*time.Second, 2),
+			expected: []api.LightStateUpdate{
+				{On: boolPtr(true)},
+				{Brightness: uint8Ptr(200), TransitionTime: transition(10)},
+				{Brightness: uint8Ptr(10), TransitionTime: transition(10)},
+				{Brightness: uint8Ptr(200), TransitionTime: transition(10)},
+				{Brightness: uint8Ptr(10), TransitionTime: transition(10)},
+			},
+			waits: []time.Duration{time.Second, time.Second, time.Second, time.Second},
+		},
+		{
+			name:   "Test strobe is rate limited",
+			effect: Strobe(20, time.Second),
+			expected: []api.LightStateUpdate{
+				{On: boolPtr(false), TransitionTime: transition(0)},
+				{On: boolPtr(true), TransitionTime: transition(0)},
+				{On: boolPtr(false), TransitionTime: transition(0)},
+				{On: boolPtr(true), TransitionTime: transition(0)},
+			},
+			waits: []time.Duration{
+				0, 250 * time.Millisecond, 0, 250 * time.Millisecond,
+				0, 250 * time.Millisecond, 0, 250 * time.Millisecond,
+			},
+		},
+		{
+			name:   "Test color cycle",
+			effect: ColorCycle([]api.CIECoord{{0.1, 0.2}, {0.3, 0.4}}, time.Second, 1),
+			expected: []api.LightStateUpdate{
+				{On: boolPtr(true)},
+				{CIECoords: xy(0.1, 0.2), TransitionTime: transition(10)},
+				{CIECoords: xy(0.3, 0.4), TransitionTime: transition(10)},
+			},
+			waits: []time.Duration{time.Second, time.Second},
+		},
+		{
+			name: "Test sequence",
+			effect: Sequence(
+				Fade(api.LightStateUpdate{On: boolPtr(true)}, 0),
+				Wait(time.Second),
+				Repeat(Fade(api.LightStateUpdate{Brightness: uint8Ptr(1)}, time.Second), 2),
+			),
+			expected: []api.LightStateUpdate{
+				{On: boolPtr(true), TransitionTime: transition(0)},
+				{Brightness: uint8Ptr(1), TransitionTime: transition(10)},
+				{Brightness: uint8Ptr(1), TransitionTime: transition(10)},
+			},
+			waits: []time.Duration{0, time.Second, time.Second, time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := fakeSleep(t)
+			target := &recordingTarget{}
+
+			err := tt.effect.Run(context.Background(), target)
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, target.updates); diff != "" {
+				t.Errorf("Updates mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.waits, *waits); diff != "" {
+				t.Errorf("Waits mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCandle(t *testing.T) {
+	fakeSleep(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Behaves like a dimmable light
+	target := &recordingTarget{
+		hook: func(update api.LightStateUpdate) error {
+			if update.Temperature != nil {
+				return fmt.Errorf("Light can't change temperature: %w", api.ErrUnsupportedFeature)
+			}
+			return nil
+		},
+	}
+
+	flickers := 0
+	hook := target.hook
+	target.hook = func(update api.LightStateUpdate) error {
+		if update.Brightness != nil {
+			flickers++
+			if flickers == 20 {
+				cancel()
+			}
+
+			if *update.Brightness < 90 || *update.Brightness > 110 {
+				t.Errorf("Expected brightness between 90 and 110 but got %d", *update.Brightness)
+			}
+		}
+		return hook(update)
+	}
+
+	err := Candle(CandleOptions{Brightness: 100, Variation: 10, Rand: rand.New(rand.NewSource(1))}, 0).Run(ctx, target)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v but got %v", context.Canceled, err)
+	}
+
+	if len(target.updates) != 21 || *target.updates[0].On != true {
+		t.Errorf("Expected the light to be turned on then flicker 20 times but got %d updates", len(target.updates))
+	}
+}
+
+func TestCandleTemperature(t *testing.T) {
+	fakeSleep(t)
+
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+	server.Bridge.PressLinkButton()
+
+	bridge := server.Endpoint(&api.API{})
+	_, err := bridge.Connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	lights, err := bridge.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	for _, light := range lights {
+		// Kitchen 2 supports 153 to 454
+		if light.ID != "2" {
+			continue
+		}
+
+		err := Candle(CandleOptions{Brightness: 100, Rand: rand.New(rand.NewSource(1))}, 100*time.Millisecond).Run(context.Background(), &light)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+
+	state := server.Bridge.Datastore().Lights["2"].State
+	if !state.On || state.Temperature != 454 {
+		t.Errorf("Expected the light to be on at 454 but got %+v", state)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	fakeSleep(t)
+
+	first := &recordingTarget{}
+	second := &recordingTarget{}
+
+	t.Run("Test effects run on their own targets", func(t *testing.T) {
+		err := Parallel(
+			On(first, Fade(api.LightStateUpdate{Brightness: uint8Ptr(1)}, time.Second)),
+			On(second, Pulse(1, 254, time.Second, 1)),
+		).Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if len(first.updates) != 1 || len(second.updates) != 3 {
+			t.Errorf("Expected 1 and 3 updates but got %d and %d", len(first.updates), len(second.updates))
+		}
+	})
+
+	t.Run("Test errors stop the other effects", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		failing := &recordingTarget{hook: func(api.LightStateUpdate) error { return errFailed }}
+
+		err := Parallel(
+			On(failing, Fade(api.LightStateUpdate{}, 0)),
+			On(second, Pulse(1, 254, time.Second, 0)),
+		).Run(context.Background(), nil)
+		if !errors.Is(err, errFailed) {
+			t.Errorf("Expected %v but got %v", errFailed, err)
+		}
+	})
+}
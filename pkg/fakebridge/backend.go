@@ -0,0 +1,213 @@
+package fakebridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// errorTypeInternal is reported when a backend fails
+const errorTypeInternal = 901
+
+// LightBackend controls the lights behind a bridge
+// A bridge with a backend acts as a proxy: its lights are read from the backend before every request and
+// state changes made through the bridge are written to the backend. Users, groups, scenes and sensors
+// are still kept in the bridge's datastore.
+type LightBackend interface {
+	// Lights returns every light keyed by ID
+	Lights() (map[string]api.Light, error)
+
+	// SetState changes the state of a light
+	SetState(id string, state api.LightState) error
+}
+
+// pull replaces the datastore's lights with the backend's (must be called with the lock held)
+func (bridge *Bridge) pull() error {
+	if bridge.Backend == nil {
+		return nil
+	}
+
+	lights, err := bridge.Backend.Lights()
+	if err != nil {
+		return err
+	}
+
+	bridge.store.Lights = lights
+	bridge.refreshGroups()
+
+	return nil
+}
+
+// lightStates returns the state of every light (must be called with the lock held)
+func (bridge *Bridge) lightStates() map[string]api.LightState {
+	states := make(map[string]api.LightState, len(bridge.store.Lights))
+	for id, light := range bridge.store.Lights {
+		states[id] = light.State
+	}
+
+	return states
+}
+
+// push writes the lights that changed since a call to lightStates to the backend (must be called with the
+// lock held)
+// A response is returned for every light the backend fails to update.
+func (bridge *Bridge) push(before map[string]api.LightState) []response {
+	if bridge.Backend == nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(bridge.store.Lights))
+	for id := range bridge.store.Lights {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	responses := []response{}
+	for _, id := range ids {
+		state := bridge.store.Lights[id].State
+		if state == before[id] {
+			continue
+		}
+
+		err := bridge.Backend.SetState(id, state)
+		if err != nil {
+			responses = append(responses, failure(errorTypeInternal, fmt.Sprintf("/lights/%s/state", id), "internal error, %s", err))
+		}
+	}
+
+	return responses
+}
+
+// FileBackend keeps lights in a JSON file
+// The file has the same format as the bridge's `/lights` resource. It's read on every request so other
+// programs (a script driving legacy devices, for example) can update it and watch it for changes.
+type FileBackend struct {
+	Path string
+}
+
+// Lights reads every light from the file
+func (backend FileBackend) Lights() (map[string]api.Light, error) {
+	data, err := ioutil.ReadFile(backend.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	lights := map[string]api.Light{}
+	err = json.Unmarshal(data, &lights)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read lights from `%s`: %w", backend.Path, err)
+	}
+
+	return lights, nil
+}
+
+// SetState writes a light's state to the file
+func (backend FileBackend) SetState(id string, state api.LightState) error {
+	lights, err := backend.Lights()
+	if err != nil {
+		return err
+	}
+
+	light, ok := lights[id]
+	if !ok {
+		return fmt.Errorf("Light `%s` not found in `%s`", id, backend.Path)
+	}
+
+	light.State = state
+	lights[id] = light
+
+	data, err := json.MarshalIndent(lights, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(backend.Path, append(data, '\n'))
+}
+
+// Message represents a state change published by a StreamBackend
+type Message struct {
+	Topic   string         `json:"topic"`
+	Payload api.LightState `json:"payload"`
+}
+
+// StreamBackend publishes state changes as MQTT style messages
+// Every change is written as a JSON encoded Message on its own line with a topic like
+// `<prefix>/lights/<id>/state`. Lights are kept in memory.
+type StreamBackend struct {
+	mu      sync.Mutex
+	prefix  string
+	lights  map[string]api.Light
+	encoder *json.Encoder
+}
+
+// NewStreamBackend creates a backend publishing changes to the given lights to a writer
+func NewStreamBackend(w io.Writer, prefix string, lights map[string]api.Light) *StreamBackend {
+	copied := make(map[string]api.Light, len(lights))
+	for id, light := range lights {
+		copied[id] = light
+	}
+
+	return &StreamBackend{
+		prefix:  prefix,
+		lights:  copied,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Lights returns every light
+func (backend *StreamBackend) Lights() (map[string]api.Light, error) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	lights := make(map[string]api.Light, len(backend.lights))
+	for id, light := range backend.lights {
+		lights[id] = light
+	}
+
+	return lights, nil
+}
+
+// SetState publishes a light's state
+func (backend *StreamBackend) SetState(id string, state api.LightState) error {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	light, ok := backend.lights[id]
+	if !ok {
+		return fmt.Errorf("Light `%s` not found", id)
+	}
+
+	light.State = state
+	backend.lights[id] = light
+
+	return backend.encoder.Encode(Message{
+		Topic:   fmt.Sprintf("%s/lights/%s/state", backend.prefix, id),
+		Payload: state,
+	})
+}
+
+// writeFile replaces a file atomically so a crash never leaves it partially written
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
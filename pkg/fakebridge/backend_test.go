@@ -0,0 +1,109 @@
+package fakebridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lights.json")
+	lights := map[string]api.Light{
+		"1": {Name: "Porch", Type: api.LightTypeDimmable, State: api.LightState{Brightness: 100, Reachable: true}},
+	}
+
+	data, _ := json.Marshal(lights)
+	err := ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	bridge := New(DefaultDatastore())
+	bridge.Backend = FileBackend{Path: path}
+	username := bridge.Register("hugh#test")
+
+	t.Run("Test lights are read from the backend", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/"+username+"/lights", nil))
+
+		got := map[string]api.Light{}
+		err := json.Unmarshal(recorder.Body.Bytes(), &got)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		if diff := cmp.Diff(lights, got); diff != "" {
+			t.Errorf("Lights mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Test state changes are written to the backend", func(t *testing.T) {
+		responses := request(t, bridge, http.MethodPut, "/api/"+username+"/lights/1/state", `{"on": true, "bri": 200}`)
+		for _, resp := range responses {
+			if resp.Error != nil {
+				t.Errorf("Expected no errors but got %+v", resp.Error)
+			}
+		}
+
+		saved, err := FileBackend{Path: path}.Lights()
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		expected := api.LightState{On: true, Brightness: 200, Reachable: true}
+		if diff := cmp.Diff(expected, saved["1"].State); diff != "" {
+			t.Errorf("State mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Test backend errors are reported", func(t *testing.T) {
+		err := ioutil.WriteFile(path, []byte("not json"), 0644)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		responses := request(t, bridge, http.MethodGet, "/api/"+username+"/lights", "")
+		if len(responses) != 1 || responses[0].Error == nil || responses[0].Error.Type != errorTypeInternal {
+			t.Errorf("Expected an internal error but got %+v", responses)
+		}
+	})
+}
+
+func TestStreamBackend(t *testing.T) {
+	var buf bytes.Buffer
+	store := DefaultDatastore()
+
+	bridge := New(store)
+	bridge.Backend = NewStreamBackend(&buf, "hugh", store.Lights)
+	username := bridge.Register("hugh#test")
+
+	request(t, bridge, http.MethodPut, "/api/"+username+"/groups/1/action", `{"on": true}`)
+
+	messages := []Message{}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var message Message
+		err := decoder.Decode(&message)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages but got %d", len(messages))
+	}
+
+	for i, topic := range []string{"hugh/lights/1/state", "hugh/lights/2/state"} {
+		if messages[i].Topic != topic || !messages[i].Payload.On {
+			t.Errorf("Expected light on at `%s` but got %+v", topic, messages[i])
+		}
+	}
+}
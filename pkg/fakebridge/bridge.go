@@ -0,0 +1,479 @@
+// Package fakebridge implements an in-memory Phillips Hue bridge
+// It serves the v1 REST API (users, config, lights, groups, scenes, schedules and sensors) so the api package
+// and the command line tools can be exercised without any hardware. With a LightBackend, a bridge can also
+// stand in front of lights that aren't Hue lights so they can be controlled by Hue apps.
+package fakebridge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// LinkButtonWindow is how long users can be created after the link button is pressed
+const LinkButtonWindow = 30 * time.Second
+
+// allLightsGroup is the special group holding every light
+const allLightsGroup = "0"
+
+// Bridge is an http.Handler serving the Hue API from a Datastore
+// A Bridge is safe for concurrent use.
+type Bridge struct {
+	// Called with a copy of the datastore after anything changes (must be set before serving requests)
+	OnChange func(Datastore)
+
+	// Where lights are read from and written to (the datastore is used if nil, must be set before serving)
+	Backend LightBackend
+
+	mu          sync.Mutex
+	store       Datastore
+	now         func() time.Time
+	linkPressed time.Time
+}
+
+// New creates a bridge serving a copy of the given datastore
+func New(store Datastore) *Bridge {
+	bridge := &Bridge{
+		store: store.copy(),
+		now:   time.Now,
+	}
+	bridge.refreshGroups()
+
+	return bridge
+}
+
+// Datastore returns a copy of everything the bridge currently knows about
+func (bridge *Bridge) Datastore() Datastore {
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+
+	return bridge.store.copy()
+}
+
+// PressLinkButton allows users to be created for the next LinkButtonWindow
+func (bridge *Bridge) PressLinkButton() {
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+
+	bridge.linkPressed = bridge.now()
+}
+
+// Register creates a user without waiting for the link button to be pressed
+func (bridge *Bridge) Register(deviceType string) string {
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+
+	username := bridge.register(deviceType)
+	bridge.changed()
+
+	return username
+}
+
+func (bridge *Bridge) register(deviceType string) string {
+	data := make([]byte, 20)
+	_, err := rand.Read(data)
+	if err != nil {
+		panic(err)
+	}
+
+	username := hex.EncodeToString(data)
+	bridge.store.Config.Whitelist[username] = WhitelistEntry{Name: deviceType}
+
+	return username
+}
+
+// response represents a single item in the bridge's response to a POST or PUT request
+type response struct {
+	Error   *api.APIError          `json:"error,omitempty"`
+	Success map[string]interface{} `json:"success,omitempty"`
+}
+
+func success(address string, value interface{}) response {
+	return response{Success: map[string]interface{}{address: value}}
+}
+
+func failure(errorType int, address, format string, args ...interface{}) response {
+	return response{Error: &api.APIError{
+		Type:        errorType,
+		Address:     address,
+		Description: fmt.Sprintf(format, args...),
+	}}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// ServeHTTP handles a request to the bridge's API
+// Like a real bridge, errors are reported in the body of responses with a 200 status.
+func (bridge *Bridge) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if parts[0] != "api" {
+		http.NotFound(w, req)
+		return
+	}
+
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+
+	if len(parts) == 1 {
+		if req.Method != http.MethodPost {
+			writeJSON(w, []response{failure(api.ErrorTypeMethodNotAvailable, "/", "method, %s, not available for resource, /", req.Method)})
+			return
+		}
+
+		writeJSON(w, []response{bridge.createUser(req)})
+		return
+	}
+
+	address := "/" + strings.Join(parts[2:], "/")
+	if _, ok := bridge.store.Config.Whitelist[parts[1]]; !ok {
+		writeJSON(w, []response{failure(api.ErrorTypeUnauthorized, address, "unauthorized user")})
+		return
+	}
+
+	err := bridge.pull()
+	if err != nil {
+		writeJSON(w, []response{failure(errorTypeInternal, address, "internal error, %s", err)})
+		return
+	}
+
+	resource := parts[2:]
+	methodNotAvailable := failure(api.ErrorTypeMethodNotAvailable, address, "method, %s, not available for resource, %s", req.Method, address)
+
+	switch req.Method {
+	case http.MethodGet:
+		v, ok := bridge.lookup(resource)
+		if !ok {
+			writeJSON(w, []response{failure(api.ErrorTypeResourceNotAvailable, address, "resource, %s, not available", address)})
+			return
+		}
+
+		writeJSON(w, v)
+	case http.MethodPut:
+		body := map[string]json.RawMessage{}
+		if json.NewDecoder(req.Body).Decode(&body) != nil {
+			writeJSON(w, []response{failure(api.ErrorTypeInvalidJSON, address, "body contains invalid json")})
+			return
+		}
+
+		before := bridge.lightStates()
+		responses := bridge.update(resource, address, body)
+		writeJSON(w, append(responses, bridge.push(before)...))
+		bridge.changed()
+	case http.MethodPost:
+		if len(resource) != 1 || resource[0] != "schedules" {
+			writeJSON(w, []response{methodNotAvailable})
+			return
+		}
+
+		writeJSON(w, []response{bridge.createSchedule(req)})
+		bridge.changed()
+	case http.MethodDelete:
+		if len(resource) != 2 || resource[0] != "schedules" {
+			writeJSON(w, []response{methodNotAvailable})
+			return
+		}
+
+		writeJSON(w, bridge.deleteSchedule(resource[1]))
+		bridge.changed()
+	default:
+		writeJSON(w, []response{methodNotAvailable})
+	}
+}
+
+// createUser handles a request to associate with the bridge
+func (bridge *Bridge) createUser(req *http.Request) response {
+	body := struct {
+		DeviceType        string `json:"devicetype"`
+		GenerateClientKey bool   `json:"generateclientkey"`
+	}{}
+
+	if json.NewDecoder(req.Body).Decode(&body) != nil {
+		return failure(api.ErrorTypeInvalidJSON, "", "body contains invalid json")
+	}
+
+	if body.DeviceType == "" {
+		return failure(api.ErrorTypeMissingParameters, "/", "invalid/missing parameters in body")
+	}
+
+	if bridge.linkPressed.IsZero() || bridge.now().Sub(bridge.linkPressed) > LinkButtonWindow {
+		return failure(api.ErrorTypeLinkButtonNotPressed, "", "link button not pressed")
+	}
+
+	username := bridge.register(body.DeviceType)
+	bridge.changed()
+
+	resp := success("username", username)
+	if body.GenerateClientKey {
+		// Streams aren't served so the key is only handed out
+		key := make([]byte, 16)
+		_, err := rand.Read(key)
+		if err != nil {
+			panic(err)
+		}
+
+		resp.Success["clientkey"] = strings.ToUpper(hex.EncodeToString(key))
+	}
+
+	return resp
+}
+
+// changed notifies OnChange (must be called with the lock held)
+func (bridge *Bridge) changed() {
+	if bridge.OnChange != nil {
+		bridge.OnChange(bridge.store.copy())
+	}
+}
+
+// lookup finds the resource at a path (relative to the user) for a GET request
+func (bridge *Bridge) lookup(path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return bridge.store, true
+	}
+
+	var collection interface{}
+	switch path[0] {
+	case "config":
+		if len(path) == 1 {
+			return bridge.store.Config, true
+		}
+
+		return nil, false
+	case "lights":
+		collection = bridge.store.Lights
+	case "groups":
+		if len(path) == 2 && path[1] == allLightsGroup {
+			return bridge.allLights(), true
+		}
+
+		collection = bridge.store.Groups
+	case "scenes":
+		if len(path) == 1 {
+			// Light states are only included when a single scene is requested
+			scenes := map[string]api.Scene{}
+			for id, scene := range bridge.store.Scenes {
+				scenes[id] = scene.Scene
+			}
+
+			return scenes, true
+		}
+
+		collection = bridge.store.Scenes
+	case "schedules":
+		collection = bridge.store.Schedules
+	case "sensors":
+		collection = bridge.store.Sensors
+	default:
+		return nil, false
+	}
+
+	if len(path) == 1 {
+		return collection, true
+	}
+
+	if len(path) > 2 {
+		return nil, false
+	}
+
+	var item interface{}
+	var ok bool
+	switch resources := collection.(type) {
+	case map[string]api.Light:
+		item, ok = resources[path[1]]
+	case map[string]api.Group:
+		item, ok = resources[path[1]]
+	case map[string]Scene:
+		item, ok = resources[path[1]]
+	case map[string]api.Schedule:
+		item, ok = resources[path[1]]
+	case map[string]api.Sensor:
+		item, ok = resources[path[1]]
+	}
+
+	return item, ok
+}
+
+// update handles a PUT request to a path (relative to the user)
+func (bridge *Bridge) update(path []string, address string, body map[string]json.RawMessage) []response {
+	switch {
+	case len(path) == 3 && path[0] == "lights" && path[2] == "state":
+		light, ok := bridge.store.Lights[path[1]]
+		if !ok {
+			break
+		}
+
+		responses := setLightState(&light, address, body)
+		bridge.store.Lights[path[1]] = light
+		bridge.refreshGroups()
+
+		return responses
+	case len(path) == 3 && path[0] == "groups" && path[2] == "action":
+		if _, ok := bridge.store.Groups[path[1]]; !ok && path[1] != allLightsGroup {
+			break
+		}
+
+		return bridge.setGroupAction(path[1], address, body)
+	case len(path) == 2 && path[0] == "groups":
+		group, ok := bridge.store.Groups[path[1]]
+		if !ok {
+			break
+		}
+
+		responses := setGroupAttributes(&group, address, body)
+		bridge.store.Groups[path[1]] = group
+
+		return responses
+	}
+
+	return []response{failure(api.ErrorTypeResourceNotAvailable, address, "resource, %s, not available", address)}
+}
+
+// setGroupAttributes applies a change to a group's attributes (only streaming is supported)
+func setGroupAttributes(group *api.Group, address string, body map[string]json.RawMessage) []response {
+	responses := []response{}
+	for key, raw := range body {
+		if key != "stream" || group.Type != api.GroupTypeEntertainment {
+			responses = append(responses, failure(api.ErrorTypeParameterNotAvailable, address+"/"+key, "parameter, %s, not available", key))
+			continue
+		}
+
+		stream := struct {
+			Active *bool `json:"active"`
+		}{}
+		if json.Unmarshal(raw, &stream) != nil || stream.Active == nil {
+			responses = append(responses, failure(api.ErrorTypeInvalidValue, address+"/stream", "invalid value, %s, for parameter, stream", raw))
+			continue
+		}
+
+		if group.Stream == nil {
+			group.Stream = &api.GroupStream{ProxyMode: "auto"}
+		}
+		group.Stream.Active = *stream.Active
+		responses = append(responses, success(address+"/stream/active", *stream.Active))
+	}
+
+	return responses
+}
+
+// allLights returns the special group holding every light
+func (bridge *Bridge) allLights() api.Group {
+	group := api.Group{
+		Name:   "Group 0",
+		Type:   api.GroupTypeLightGroup,
+		Lights: []string{},
+		Action: api.LightState{Alert: api.AlertNone},
+	}
+
+	for id := range bridge.store.Lights {
+		group.Lights = append(group.Lights, id)
+	}
+	sort.Strings(group.Lights)
+
+	group.State = bridge.groupState(group.Lights)
+
+	return group
+}
+
+func (bridge *Bridge) groupState(ids []string) api.GroupState {
+	state := api.GroupState{AllOn: len(ids) > 0}
+	for _, id := range ids {
+		on := bridge.store.Lights[id].State.On
+		state.AllOn = state.AllOn && on
+		state.AnyOn = state.AnyOn || on
+	}
+
+	return state
+}
+
+// refreshGroups updates the state of every group after its lights change
+func (bridge *Bridge) refreshGroups() {
+	for id, group := range bridge.store.Groups {
+		group.State = bridge.groupState(group.Lights)
+		bridge.store.Groups[id] = group
+	}
+}
+
+// setGroupAction applies a change to every light in a group
+// Like a real bridge, lights that can't make the change are skipped rather than reported as errors.
+func (bridge *Bridge) setGroupAction(id, address string, body map[string]json.RawMessage) []response {
+	var group api.Group
+	if id == allLightsGroup {
+		group = bridge.allLights()
+	} else {
+		group = bridge.store.Groups[id]
+	}
+
+	if raw, ok := body["scene"]; ok {
+		return []response{bridge.recallScene(group, id, address, raw, body["transitiontime"])}
+	}
+
+	for _, lightID := range group.Lights {
+		light, ok := bridge.store.Lights[lightID]
+		if !ok {
+			continue
+		}
+
+		setLightState(&light, "", body)
+		bridge.store.Lights[lightID] = light
+	}
+
+	// The action always reflects the requested change (whether or not the lights support it)
+	allFeatures := api.FeatureOnOff | api.FeatureBrightness | api.FeatureColorTemperature | api.FeatureColor
+	responses := applyState(&group.Action, allFeatures, api.DefaultTemperatureRange, false, address, body)
+
+	if id != allLightsGroup {
+		bridge.store.Groups[id] = group
+	}
+	bridge.refreshGroups()
+
+	return responses
+}
+
+// recallScene applies a scene's light states to the lights of a group that are part of the scene
+func (bridge *Bridge) recallScene(group api.Group, groupID, address string, raw, transitionTime json.RawMessage) response {
+	var sceneID string
+	if json.Unmarshal(raw, &sceneID) != nil {
+		return failure(api.ErrorTypeInvalidValue, address+"/scene", "invalid value, %s, for parameter, scene", raw)
+	}
+
+	scene, ok := bridge.store.Scenes[sceneID]
+	if !ok {
+		return failure(api.ErrorTypeResourceNotAvailable, address+"/scene", "resource, /scenes/%s, not available", sceneID)
+	}
+
+	members := map[string]bool{}
+	for _, id := range group.Lights {
+		members[id] = true
+	}
+
+	for lightID, update := range scene.LightStates {
+		light, ok := bridge.store.Lights[lightID]
+		if !ok || !members[lightID] {
+			continue
+		}
+
+		body := map[string]json.RawMessage{}
+		data, _ := json.Marshal(update)
+		json.Unmarshal(data, &body)
+		if transitionTime != nil {
+			body["transitiontime"] = transitionTime
+		}
+
+		setLightState(&light, "", body)
+		bridge.store.Lights[lightID] = light
+	}
+
+	bridge.refreshGroups()
+
+	return success(address+"/scene", sceneID)
+}
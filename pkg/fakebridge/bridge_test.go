@@ -0,0 +1,257 @@
+package fakebridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/google/go-cmp/cmp"
+)
+
+func request(t *testing.T, bridge *Bridge, method, path, body string) []response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	recorder := httptest.NewRecorder()
+	bridge.ServeHTTP(recorder, req)
+
+	responses := []response{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &responses)
+	if err != nil {
+		t.Fatalf("Expected a list of responses but got `%s`", recorder.Body.String())
+	}
+
+	return responses
+}
+
+func TestLinkButton(t *testing.T) {
+	bridge := New(DefaultDatastore())
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	bridge.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		setup    func()
+		expected int
+	}{
+		{
+			name:     "Test link button not pressed",
+			setup:    func() {},
+			expected: api.ErrorTypeLinkButtonNotPressed,
+		},
+		{
+			name:  "Test link button pressed",
+			setup: bridge.PressLinkButton,
+		},
+		{
+			name:     "Test link button window expired",
+			setup:    func() { now = now.Add(LinkButtonWindow + time.Second) },
+			expected: api.ErrorTypeLinkButtonNotPressed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			responses := request(t, bridge, http.MethodPost, "/api", `{"devicetype": "hugh#test"}`)
+			if len(responses) != 1 {
+				t.Fatalf("Expected 1 response but got %d", len(responses))
+			}
+
+			if tt.expected != 0 {
+				if responses[0].Error == nil || responses[0].Error.Type != tt.expected {
+					t.Errorf("Expected error type %d but got %+v", tt.expected, responses[0])
+				}
+				return
+			}
+
+			username, ok := responses[0].Success["username"].(string)
+			if !ok {
+				t.Fatalf("Expected a username but got %+v", responses[0])
+			}
+
+			if _, ok := bridge.Datastore().Config.Whitelist[username]; !ok {
+				t.Errorf("Expected `%s` to be whitelisted", username)
+			}
+		})
+	}
+}
+
+func TestSetLightState(t *testing.T) {
+	tests := []struct {
+		name      string
+		light     string
+		body      string
+		responses []response
+		expected  api.LightState
+	}{
+		{
+			name:  "Test turning on and dimming",
+			light: "3",
+			body:  `{"on": true, "bri": 300, "transitiontime": 10}`,
+			responses: []response{
+				success("/lights/3/state/bri", 254.0),
+				success("/lights/3/state/on", true),
+				success("/lights/3/state/transitiontime", 10.0),
+			},
+			expected: api.LightState{On: true, Brightness: 254, Alert: "none", Mode: "homeautomation", Reachable: true},
+		},
+		{
+			name:  "Test light is off",
+			light: "3",
+			body:  `{"bri": 10}`,
+			responses: []response{
+				failure(api.ErrorTypeDeviceOff, "/lights/3/state/bri", "parameter, bri, is not modifiable. Device is set to off."),
+			},
+			expected: api.LightState{Brightness: 254, Alert: "none", Mode: "homeautomation", Reachable: true},
+		},
+		{
+			name:  "Test unsupported parameter",
+			light: "3",
+			body:  `{"on": true, "ct": 300}`,
+			responses: []response{
+				failure(api.ErrorTypeParameterNotAvailable, "/lights/3/state/ct", "parameter, ct, not available"),
+				success("/lights/3/state/on", true),
+			},
+			expected: api.LightState{On: true, Brightness: 254, Alert: "none", Mode: "homeautomation", Reachable: true},
+		},
+		{
+			name:  "Test relative changes",
+			light: "2",
+			body:  `{"on": true, "bri_inc": -300, "ct_inc": 200}`,
+			responses: []response{
+				success("/lights/2/state/bri", 1.0),
+				success("/lights/2/state/ct", 454.0),
+				success("/lights/2/state/on", true),
+			},
+			expected: api.LightState{On: true, Brightness: 1, Temperature: 454, Alert: "none", ColorMode: "ct", Mode: "homeautomation", Reachable: true},
+		},
+		{
+			name:  "Test invalid value",
+			light: "1",
+			body:  `{"on": true, "alert": "blink"}`,
+			responses: []response{
+				failure(api.ErrorTypeInvalidValue, "/lights/1/state/alert", `invalid value, "blink", for parameter, alert`),
+				success("/lights/1/state/on", true),
+			},
+			expected: api.LightState{
+				On:          true,
+				Brightness:  254,
+				Hue:         8418,
+				Saturation:  140,
+				CIECoords:   api.CIECoord{0.4573, 0.41},
+				Temperature: 366,
+				Alert:       "none",
+				Effect:      "none",
+				ColorMode:   "ct",
+				Mode:        "homeautomation",
+				Reachable:   true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bridge := New(DefaultDatastore())
+			username := bridge.Register("hugh#test")
+
+			responses := request(t, bridge, http.MethodPut, "/api/"+username+"/lights/"+tt.light+"/state", tt.body)
+			if diff := cmp.Diff(tt.responses, responses); diff != "" {
+				t.Errorf("Responses mismatch (-want +got):\n%s", diff)
+			}
+
+			state := bridge.Datastore().Lights[tt.light].State
+			if diff := cmp.Diff(tt.expected, state); diff != "" {
+				t.Errorf("State mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	bridge := New(DefaultDatastore())
+	username := bridge.Register("hugh#test")
+
+	t.Run("Test group action", func(t *testing.T) {
+		request(t, bridge, http.MethodPut, "/api/"+username+"/groups/2/action", `{"on": true, "ct": 250}`)
+
+		store := bridge.Datastore()
+		for _, id := range []string{"1", "2", "3"} {
+			if !store.Lights[id].State.On {
+				t.Errorf("Expected light %s to be on", id)
+			}
+		}
+
+		if store.Lights["2"].State.Temperature != 250 {
+			t.Errorf("Expected temperature 250 but got %d", store.Lights["2"].State.Temperature)
+		}
+
+		if !store.Groups["1"].State.AllOn || !store.Groups["2"].State.AllOn {
+			t.Errorf("Expected groups to be on but got %+v", store.Groups)
+		}
+	})
+
+	t.Run("Test scene recall", func(t *testing.T) {
+		request(t, bridge, http.MethodPut, "/api/"+username+"/groups/1/action", `{"scene": "relax"}`)
+
+		store := bridge.Datastore()
+		if store.Lights["2"].State.Brightness != 1 || store.Lights["2"].State.Temperature != 447 {
+			t.Errorf("Expected scene to be recalled but got %+v", store.Lights["2"].State)
+		}
+	})
+
+	t.Run("Test unauthorized user", func(t *testing.T) {
+		responses := request(t, bridge, http.MethodGet, "/api/nobody/groups", "")
+		if len(responses) != 1 || responses[0].Error == nil || responses[0].Error.Type != api.ErrorTypeUnauthorized {
+			t.Errorf("Expected an unauthorized error but got %+v", responses)
+		}
+	})
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datastore.json")
+
+	err := DefaultDatastore().Save(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	store, err := LoadDatastore(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	bridge := New(store)
+	bridge.OnChange = func(store Datastore) {
+		err := store.Save(path)
+		if err != nil {
+			t.Errorf("Expected no error but got %v", err)
+		}
+	}
+
+	username := bridge.Register("hugh#test")
+	request(t, bridge, http.MethodPut, "/api/"+username+"/lights/3/state", `{"on": true}`)
+
+	saved, err := LoadDatastore(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	if _, ok := saved.Config.Whitelist[username]; !ok {
+		t.Errorf("Expected `%s` to be saved", username)
+	}
+
+	if !saved.Lights["3"].State.On {
+		t.Error("Expected light to be saved as on")
+	}
+
+	if diff := cmp.Diff(bridge.Datastore(), saved); diff != "" {
+		t.Errorf("Datastore mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,259 @@
+package fakebridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// Config represents the bridge's configuration
+type Config struct {
+	Name      string                    `json:"name"`
+	BridgeID  string                    `json:"bridgeid"`
+	ModelID   string                    `json:"modelid"`
+	Whitelist map[string]WhitelistEntry `json:"whitelist"`
+}
+
+// ServiceText returns the TXT records a bridge with this configuration advertises over mDNS
+func (config Config) ServiceText() []string {
+	return []string{
+		fmt.Sprintf("bridgeid=%s", strings.ToLower(config.BridgeID)),
+		fmt.Sprintf("modelid=%s", config.ModelID),
+	}
+}
+
+// WhitelistEntry represents a user allowed to use the bridge's API
+type WhitelistEntry struct {
+	Name string `json:"name"` // The device type sent when the user was created
+}
+
+// Scene represents a scene along with the light states it recalls
+type Scene struct {
+	api.Scene
+	LightStates map[string]api.LightStateUpdate `json:"lightstates,omitempty"`
+}
+
+// Datastore holds everything a bridge knows about
+// Resources are keyed by their ID like they are in the bridge's responses.
+type Datastore struct {
+	Config    Config                  `json:"config"`
+	Lights    map[string]api.Light    `json:"lights"`
+	Groups    map[string]api.Group    `json:"groups"`
+	Scenes    map[string]Scene        `json:"scenes"`
+	Schedules map[string]api.Schedule `json:"schedules"`
+	Sensors   map[string]api.Sensor   `json:"sensors"`
+}
+
+// LoadDatastore reads a datastore from a JSON file
+func LoadDatastore(path string) (Datastore, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Datastore{}, err
+	}
+
+	var store Datastore
+	err = json.Unmarshal(data, &store)
+	if err != nil {
+		return Datastore{}, fmt.Errorf("Failed to read datastore `%s`: %w", path, err)
+	}
+	store.init()
+
+	return store, nil
+}
+
+// Save writes the datastore to a JSON file
+// The file is replaced atomically so a crash never leaves a partially written datastore behind.
+func (store Datastore) Save(path string) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, append(data, '\n'))
+}
+
+// copy returns a deep copy of the datastore
+// Round tripping through JSON keeps this correct as the api package's types gain fields.
+func (store Datastore) copy() Datastore {
+	data, err := json.Marshal(store)
+	if err != nil {
+		panic(err)
+	}
+
+	var copied Datastore
+	err = json.Unmarshal(data, &copied)
+	if err != nil {
+		panic(err)
+	}
+
+	copied.init()
+
+	return copied
+}
+
+// init makes sure none of the datastore's maps are nil
+func (store *Datastore) init() {
+	if store.Config.Whitelist == nil {
+		store.Config.Whitelist = map[string]WhitelistEntry{}
+	}
+
+	if store.Lights == nil {
+		store.Lights = map[string]api.Light{}
+	}
+
+	if store.Groups == nil {
+		store.Groups = map[string]api.Group{}
+	}
+
+	if store.Scenes == nil {
+		store.Scenes = map[string]Scene{}
+	}
+
+	if store.Schedules == nil {
+		store.Schedules = map[string]api.Schedule{}
+	}
+
+	if store.Sensors == nil {
+		store.Sensors = map[string]api.Sensor{}
+	}
+}
+
+func colorCapabilities(gamut string) api.LightCapabilities {
+	return api.LightCapabilities{
+		Certified: true,
+		Control: api.LightCapabilitiesControl{
+			MinimumDim:     1000,
+			MaxLumen:       800,
+			ColorGamutType: gamut,
+			ColorGamuts: [3]api.LightColorGamut{
+				{0.6915, 0.3083},
+				{0.17, 0.7},
+				{0.1532, 0.0475},
+			},
+			TemperatureRange: api.LightTemperatureRange{Minimum: 153, Maximum: 454},
+		},
+		Streaming: api.LightStreamingCapabilities{Renderer: true},
+	}
+}
+
+// DefaultDatastore returns a small home with a light of each kind, a room, a zone, a scene and a sensor
+func DefaultDatastore() Datastore {
+	one := uint8(1)
+	full := uint8(254)
+	on := true
+	relax := uint16(447)
+
+	return Datastore{
+		Config: Config{
+			Name:      "Fake Hue Bridge",
+			BridgeID:  "001788FFFE000000",
+			ModelID:   "BSB002",
+			Whitelist: map[string]WhitelistEntry{},
+		},
+		Lights: map[string]api.Light{
+			"1": {
+				Name:         "Kitchen 1",
+				Type:         api.LightTypeExtendedColor,
+				ModelID:      "LCA001",
+				Manufacturer: "Signify Netherlands B.V.",
+				Product:      "Hue color lamp",
+				Capabilities: colorCapabilities("C"),
+				State: api.LightState{
+					Brightness:  254,
+					Hue:         8418,
+					Saturation:  140,
+					CIECoords:   api.CIECoord{0.4573, 0.41},
+					Temperature: 366,
+					Alert:       api.AlertNone,
+					Effect:      api.EffectNone,
+					ColorMode:   "ct",
+					Mode:        "homeautomation",
+					Reachable:   true,
+				},
+				UID: "00:17:88:01:00:00:00:01-0b",
+			},
+			"2": {
+				Name:         "Kitchen 2",
+				Type:         api.LightTypeColorTemperature,
+				ModelID:      "LTW001",
+				Manufacturer: "Signify Netherlands B.V.",
+				Product:      "Hue ambiance lamp",
+				Capabilities: api.LightCapabilities{
+					Certified: true,
+					Control: api.LightCapabilitiesControl{
+						MinimumDim:       1000,
+						MaxLumen:         806,
+						TemperatureRange: api.LightTemperatureRange{Minimum: 153, Maximum: 454},
+					},
+				},
+				State: api.LightState{
+					Brightness:  254,
+					Temperature: 366,
+					Alert:       api.AlertNone,
+					ColorMode:   "ct",
+					Mode:        "homeautomation",
+					Reachable:   true,
+				},
+				UID: "00:17:88:01:00:00:00:02-0b",
+			},
+			"3": {
+				Name:         "Hallway",
+				Type:         api.LightTypeDimmable,
+				ModelID:      "LWB010",
+				Manufacturer: "Signify Netherlands B.V.",
+				Product:      "Hue white lamp",
+				State: api.LightState{
+					Brightness: 254,
+					Alert:      api.AlertNone,
+					Mode:       "homeautomation",
+					Reachable:  true,
+				},
+				UID: "00:17:88:01:00:00:00:03-0b",
+			},
+		},
+		Groups: map[string]api.Group{
+			"1": {
+				Name:   "Kitchen",
+				Lights: []string{"1", "2"},
+				Type:   api.GroupTypeRoom,
+				Class:  "Kitchen",
+				Action: api.LightState{Brightness: 254, Temperature: 366, Alert: api.AlertNone, ColorMode: "ct"},
+			},
+			"2": {
+				Name:   "Downstairs",
+				Lights: []string{"1", "2", "3"},
+				Type:   api.GroupTypeZone,
+				Class:  "Downstairs",
+				Action: api.LightState{Brightness: 254, Alert: api.AlertNone},
+			},
+		},
+		Scenes: map[string]Scene{
+			"relax": {
+				Scene: api.Scene{
+					Name:    "Relax",
+					Type:    api.SceneTypeGroup,
+					Group:   "1",
+					Lights:  []string{"1", "2"},
+					Version: 2,
+				},
+				LightStates: map[string]api.LightStateUpdate{
+					"1": {On: &on, Brightness: &full, Temperature: &relax},
+					"2": {On: &on, Brightness: &one, Temperature: &relax},
+				},
+			},
+		},
+		Sensors: map[string]api.Sensor{
+			"1": {
+				Name:      "Daylight",
+				Type:      "Daylight",
+				ModelID:   "PHDL00",
+				SWVersion: "1.0",
+				State:     map[string]interface{}{"daylight": true, "lastupdated": "none"},
+				Config:    map[string]interface{}{"on": true, "configured": false},
+			},
+		},
+	}
+}
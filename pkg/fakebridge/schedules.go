@@ -0,0 +1,170 @@
+package fakebridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// scheduleTimeLayout is the format of a schedule's local time (only schedules that trigger once are supported)
+const scheduleTimeLayout = "2006-01-02T15:04:05"
+
+// createSchedule handles a request to create a schedule (must be called with the lock held)
+func (bridge *Bridge) createSchedule(req *http.Request) response {
+	body := struct {
+		Name        string               `json:"name"`
+		Description string               `json:"description"`
+		Command     *api.ScheduleCommand `json:"command"`
+		LocalTime   string               `json:"localtime"`
+		Status      string               `json:"status"`
+		AutoDelete  *bool                `json:"autodelete"`
+		Recycle     bool                 `json:"recycle"`
+	}{}
+
+	if json.NewDecoder(req.Body).Decode(&body) != nil {
+		return failure(api.ErrorTypeInvalidJSON, "/schedules", "body contains invalid json")
+	}
+
+	if body.Command == nil || body.LocalTime == "" {
+		return failure(api.ErrorTypeMissingParameters, "/schedules", "invalid/missing parameters in body")
+	}
+
+	if _, err := time.Parse(scheduleTimeLayout, body.LocalTime); err != nil {
+		return failure(api.ErrorTypeInvalidValue, "/schedules/localtime", "invalid value, %s, for parameter, localtime", body.LocalTime)
+	}
+
+	if body.Command.Method != http.MethodPut || !strings.HasPrefix(body.Command.Address, "/api/") {
+		return failure(api.ErrorTypeInvalidValue, "/schedules/command", "invalid value, %s %s, for parameter, command", body.Command.Method, body.Command.Address)
+	}
+
+	schedule := api.Schedule{
+		Name:        body.Name,
+		Description: body.Description,
+		Command:     *body.Command,
+		LocalTime:   body.LocalTime,
+		Status:      body.Status,
+		AutoDelete:  true,
+		Recycle:     body.Recycle,
+	}
+	schedule.Created.Time = bridge.now().UTC().Truncate(time.Second)
+
+	if schedule.Name == "" {
+		schedule.Name = "schedule"
+	}
+
+	if schedule.Status == "" {
+		schedule.Status = api.ScheduleStatusEnabled
+	}
+
+	if body.AutoDelete != nil {
+		schedule.AutoDelete = *body.AutoDelete
+	}
+
+	id := 1
+	for {
+		if _, ok := bridge.store.Schedules[strconv.Itoa(id)]; !ok {
+			break
+		}
+		id++
+	}
+
+	bridge.store.Schedules[strconv.Itoa(id)] = schedule
+
+	return success("id", strconv.Itoa(id))
+}
+
+// deleteSchedule handles a request to delete a schedule (must be called with the lock held)
+// Successful deletions are reported as a plain string rather than an object.
+func (bridge *Bridge) deleteSchedule(id string) interface{} {
+	address := fmt.Sprintf("/schedules/%s", id)
+	if _, ok := bridge.store.Schedules[id]; !ok {
+		return []response{failure(api.ErrorTypeResourceNotAvailable, address, "resource, %s, not available", address)}
+	}
+
+	delete(bridge.store.Schedules, id)
+
+	return []map[string]string{{"success": fmt.Sprintf("%s deleted", address)}}
+}
+
+// RunSchedules runs the commands of every enabled schedule that's due
+// Schedule times are interpreted in the location of the bridge's clock (the local timezone by default).
+// Schedules are run in order of their times and then deleted or disabled depending on their autodelete
+// attribute. A real bridge does this on its own so a server should call this every second or so.
+func (bridge *Bridge) RunSchedules() {
+	bridge.mu.Lock()
+	defer bridge.mu.Unlock()
+
+	now := bridge.now()
+
+	due := []string{}
+	for id, schedule := range bridge.store.Schedules {
+		at, err := time.ParseInLocation(scheduleTimeLayout, schedule.LocalTime, now.Location())
+		if err != nil || schedule.Status != api.ScheduleStatusEnabled || at.After(now) {
+			continue
+		}
+
+		due = append(due, id)
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		first, second := bridge.store.Schedules[due[i]], bridge.store.Schedules[due[j]]
+		if first.LocalTime != second.LocalTime {
+			return first.LocalTime < second.LocalTime
+		}
+
+		return due[i] < due[j]
+	})
+
+	if bridge.pull() == nil {
+		before := bridge.lightStates()
+
+		for _, id := range due {
+			bridge.runCommand(bridge.store.Schedules[id].Command)
+		}
+
+		bridge.push(before)
+	}
+
+	for _, id := range due {
+		schedule := bridge.store.Schedules[id]
+		if schedule.AutoDelete {
+			delete(bridge.store.Schedules, id)
+			continue
+		}
+
+		schedule.Status = api.ScheduleStatusDisabled
+		bridge.store.Schedules[id] = schedule
+	}
+
+	bridge.changed()
+}
+
+// runCommand sends a schedule's command to the bridge (must be called with the lock held)
+// Commands are ignored if their user no longer exists or their body isn't a JSON object.
+func (bridge *Bridge) runCommand(command api.ScheduleCommand) {
+	parts := strings.Split(strings.Trim(command.Address, "/"), "/")
+	if len(parts) < 3 || parts[0] != "api" {
+		return
+	}
+
+	if _, ok := bridge.store.Config.Whitelist[parts[1]]; !ok {
+		return
+	}
+
+	body := map[string]json.RawMessage{}
+	if json.Unmarshal(command.Body, &body) != nil {
+		return
+	}
+
+	bridge.update(parts[2:], "/"+strings.Join(parts[2:], "/"), body)
+}
@@ -0,0 +1,105 @@
+package fakebridge
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+func TestSchedules(t *testing.T) {
+	bridge := New(DefaultDatastore())
+	now := time.Date(2021, 3, 1, 6, 0, 0, 0, time.UTC)
+	bridge.now = func() time.Time { return now }
+	username := bridge.Register("hugh#test")
+
+	schedule := func(localTime, body string, autoDelete bool) string {
+		return fmt.Sprintf(`{
+			"name": "Test",
+			"command": {"address": "/api/%s/groups/1/action", "method": "PUT", "body": %s},
+			"localtime": "%s",
+			"autodelete": %v
+		}`, username, body, localTime, autoDelete)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{
+			name:     "Test missing command",
+			body:     `{"localtime": "2021-03-01T06:30:00"}`,
+			expected: api.ErrorTypeMissingParameters,
+		},
+		{
+			name:     "Test recurring schedule",
+			body:     schedule("W124/T06:30:00", `{"on": true}`, true),
+			expected: api.ErrorTypeInvalidValue,
+		},
+		{
+			name: "Test schedule",
+			body: schedule("2021-03-01T06:30:00", `{"on": true, "bri": 100}`, true),
+		},
+		{
+			name: "Test schedule kept after running",
+			body: schedule("2021-03-01T06:40:00", `{"bri": 200}`, false),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := request(t, bridge, http.MethodPost, "/api/"+username+"/schedules", tt.body)
+			if len(responses) != 1 {
+				t.Fatalf("Expected a single response but got %+v", responses)
+			}
+
+			if tt.expected == 0 && responses[0].Error != nil {
+				t.Errorf("Expected no error but got %v", responses[0].Error)
+			}
+
+			if tt.expected != 0 && (responses[0].Error == nil || responses[0].Error.Type != tt.expected) {
+				t.Errorf("Expected error type %d but got %+v", tt.expected, responses[0])
+			}
+		})
+	}
+
+	t.Run("Test schedules run once due", func(t *testing.T) {
+		bridge.RunSchedules()
+		if bridge.Datastore().Lights["1"].State.On {
+			t.Fatal("Expected schedule not to run early")
+		}
+
+		now = now.Add(time.Hour)
+		bridge.RunSchedules()
+
+		store := bridge.Datastore()
+		if !store.Lights["1"].State.On || store.Lights["1"].State.Brightness != 200 {
+			t.Errorf("Expected both schedules to run in order but got %+v", store.Lights["1"].State)
+		}
+
+		if _, ok := store.Schedules["1"]; ok {
+			t.Error("Expected schedule 1 to be deleted")
+		}
+
+		if store.Schedules["2"].Status != api.ScheduleStatusDisabled {
+			t.Errorf("Expected schedule 2 to be disabled but got %+v", store.Schedules["2"])
+		}
+	})
+
+	t.Run("Test delete", func(t *testing.T) {
+		for _, expected := range []string{`[{"success":"/schedules/2 deleted"}]`, `[{"error":{"type":3,"address":"/schedules/2","description":"resource, /schedules/2, not available"}}]`} {
+			req := httptest.NewRequest(http.MethodDelete, "/api/"+username+"/schedules/2", nil)
+			recorder := httptest.NewRecorder()
+			bridge.ServeHTTP(recorder, req)
+
+			if body := strings.TrimSpace(recorder.Body.String()); body != expected {
+				t.Errorf("Expected `%s` but got `%s`", expected, body)
+			}
+		}
+	})
+}
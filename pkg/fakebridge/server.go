@@ -0,0 +1,101 @@
+package fakebridge
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/grandcat/zeroconf"
+)
+
+// Server serves a Bridge on a local port
+type Server struct {
+	Bridge *Bridge
+	HTTP   *httptest.Server
+}
+
+// NewServer starts serving a bridge holding a copy of the given datastore
+// The server should be closed when it's no longer needed.
+func NewServer(store Datastore) *Server {
+	bridge := New(store)
+
+	return &Server{
+		Bridge: bridge,
+		HTTP:   httptest.NewServer(bridge),
+	}
+}
+
+// Close shuts down the server
+func (server *Server) Close() {
+	server.HTTP.Close()
+}
+
+// Addr returns the address the server is listening on
+func (server *Server) Addr() *net.TCPAddr {
+	return server.HTTP.Listener.Addr().(*net.TCPAddr)
+}
+
+// ServiceEntry returns the mDNS entry a bridge at the server's address would advertise
+func (server *Server) ServiceEntry() *zeroconf.ServiceEntry {
+	config := server.Bridge.Datastore().Config
+
+	entry := zeroconf.NewServiceEntry(config.Name, "_hue._tcp", "local.")
+	entry.Port = server.Addr().Port
+	entry.AddrIPv4 = []net.IP{server.Addr().IP}
+	entry.Text = config.ServiceText()
+
+	return entry
+}
+
+// API returns an API that discovers the server (and nothing else)
+func (server *Server) API() *api.API {
+	return &api.API{
+		Browser:        &Browser{Entries: []*zeroconf.ServiceEntry{server.ServiceEntry()}},
+		TimeoutSeconds: 1,
+	}
+}
+
+// Endpoint returns a bridge that sends requests to the server using the given API
+func (server *Server) Endpoint(hue *api.API) *api.Bridge {
+	config := server.Bridge.Datastore().Config
+
+	return &api.Bridge{
+		API:   hue,
+		ID:    strings.ToLower(config.BridgeID),
+		Model: config.ModelID,
+		IP:    server.Addr().IP,
+		Port:  server.Addr().Port,
+	}
+}
+
+// Browser is a stand-in for an mDNS browser that finds a fixed set of services
+type Browser struct {
+	Entries []*zeroconf.ServiceEntry
+}
+
+// Browse sends the entries matching a service and domain then waits for the context to be done
+// Like zeroconf's resolver, entries are sent in the background and the channel is closed once the context
+// is done.
+func (browser *Browser) Browse(ctx context.Context, service, domain string, entries chan<- *zeroconf.ServiceEntry) error {
+	go func() {
+		defer close(entries)
+
+		for _, entry := range browser.Entries {
+			if entry.Service != service || strings.TrimSuffix(entry.Domain, ".") != strings.TrimSuffix(domain, ".") {
+				continue
+			}
+
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		<-ctx.Done()
+	}()
+
+	return nil
+}
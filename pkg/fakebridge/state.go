@@ -0,0 +1,183 @@
+package fakebridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+)
+
+// The feature a light needs for each parameter of a state change
+var parameterFeatures = map[string]api.Feature{
+	"on":             api.FeatureOnOff,
+	"alert":          api.FeatureOnOff,
+	"transitiontime": api.FeatureOnOff,
+	"bri":            api.FeatureBrightness,
+	"bri_inc":        api.FeatureBrightness,
+	"ct":             api.FeatureColorTemperature,
+	"ct_inc":         api.FeatureColorTemperature,
+	"hue":            api.FeatureColor,
+	"hue_inc":        api.FeatureColor,
+	"sat":            api.FeatureColor,
+	"sat_inc":        api.FeatureColor,
+	"xy":             api.FeatureColor,
+	"xy_inc":         api.FeatureColor,
+	"effect":         api.FeatureColor,
+}
+
+// setLightState applies a state change to a light
+// Changes take effect immediately (transition times are accepted but the light doesn't fade).
+func setLightState(light *api.Light, address string, body map[string]json.RawMessage) []response {
+	return applyState(&light.State, light.Features(), light.TemperatureRange(), true, address, body)
+}
+
+// applyState applies each parameter of a state change, returning a response for every parameter
+// Relative changes are reported with the value they result in (`bri_inc` is reported as `bri`, etc.)
+// If requireOn is set, parameters other than `on` are rejected unless the light is on or being turned on.
+func applyState(state *api.LightState, features api.Feature, tempRange api.LightTemperatureRange, requireOn bool, address string, body map[string]json.RawMessage) []response {
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	turningOn := false
+	if raw, ok := body["on"]; ok {
+		json.Unmarshal(raw, &turningOn)
+	}
+
+	responses := []response{}
+	for _, key := range keys {
+		raw := body[key]
+		parameterAddress := fmt.Sprintf("%s/%s", address, key)
+
+		feature, ok := parameterFeatures[key]
+		if !ok || !features.Has(feature) {
+			responses = append(responses, failure(api.ErrorTypeParameterNotAvailable, parameterAddress, "parameter, %s, not available", key))
+			continue
+		}
+
+		if requireOn && !state.On && !turningOn && key != "on" {
+			responses = append(responses, failure(api.ErrorTypeDeviceOff, parameterAddress, "parameter, %s, is not modifiable. Device is set to off.", key))
+			continue
+		}
+
+		value, err := applyParameter(state, tempRange, key, raw)
+		if err != nil {
+			responses = append(responses, failure(api.ErrorTypeInvalidValue, parameterAddress, "invalid value, %s, for parameter, %s", raw, key))
+			continue
+		}
+
+		responses = append(responses, success(fmt.Sprintf("%s/%s", address, strings.TrimSuffix(key, "_inc")), value))
+	}
+
+	return responses
+}
+
+// applyParameter applies a single parameter of a state change and returns the resulting value
+func applyParameter(state *api.LightState, tempRange api.LightTemperatureRange, key string, raw json.RawMessage) (interface{}, error) {
+	switch key {
+	case "on":
+		err := json.Unmarshal(raw, &state.On)
+		return state.On, err
+	case "transitiontime":
+		var value uint16
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	case "alert", "effect":
+		var value string
+		err := json.Unmarshal(raw, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "alert" {
+			if value != api.AlertNone && value != api.AlertSelect && value != api.AlertLong {
+				return nil, fmt.Errorf("Invalid alert `%s`", value)
+			}
+			state.Alert = value
+		} else {
+			if value != api.EffectNone && value != api.EffectColorLoop {
+				return nil, fmt.Errorf("Invalid effect `%s`", value)
+			}
+			state.Effect = value
+			state.ColorMode = "hs"
+		}
+
+		return value, nil
+	case "xy", "xy_inc":
+		var value api.CIECoord
+		err := json.Unmarshal(raw, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "xy_inc" {
+			value = api.CIECoord{state.CIECoords[0] + value[0], state.CIECoords[1] + value[1]}
+		}
+
+		for i := range value {
+			value[i] = math.Max(0, math.Min(1, value[i]))
+		}
+
+		state.CIECoords = value
+		state.ColorMode = "xy"
+
+		return value, nil
+	}
+
+	// Everything else is a number (absolute or relative)
+	var value int64
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key {
+	case "bri":
+		state.Brightness = uint8(clamp(value, api.MinimumBrightness, api.MaximumBrightness))
+		return state.Brightness, nil
+	case "bri_inc":
+		state.Brightness = uint8(clamp(int64(state.Brightness)+value, api.MinimumBrightness, api.MaximumBrightness))
+		return state.Brightness, nil
+	case "sat":
+		state.Saturation = uint8(clamp(value, 0, api.MaximumSaturation))
+	case "sat_inc":
+		state.Saturation = uint8(clamp(int64(state.Saturation)+value, 0, api.MaximumSaturation))
+	case "hue":
+		state.Hue = uint16(clamp(value, 0, api.MaximumHue))
+	case "hue_inc":
+		// Hue wraps around like the color wheel it represents
+		state.Hue = uint16(((int64(state.Hue)+value)%(api.MaximumHue+1) + api.MaximumHue + 1) % (api.MaximumHue + 1))
+	case "ct":
+		state.Temperature = uint16(clamp(value, int64(tempRange.Minimum), int64(tempRange.Maximum)))
+		state.ColorMode = "ct"
+		return state.Temperature, nil
+	case "ct_inc":
+		state.Temperature = uint16(clamp(int64(state.Temperature)+value, int64(tempRange.Minimum), int64(tempRange.Maximum)))
+		state.ColorMode = "ct"
+		return state.Temperature, nil
+	}
+
+	state.ColorMode = "hs"
+	if strings.HasPrefix(key, "hue") {
+		return state.Hue, nil
+	}
+
+	return state.Saturation, nil
+}
+
+func clamp(value, min, max int64) int64 {
+	if value < min {
+		return min
+	}
+
+	if value > max {
+		return max
+	}
+
+	return value
+}
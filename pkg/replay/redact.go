@@ -0,0 +1,163 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Attributes holding usernames in the bridge's responses (scenes, rules, schedules, etc.)
+var usernameKeys = map[string]bool{
+	"owner": true,
+}
+
+// redactor replaces usernames with stable aliases
+// The username used to talk to the bridge becomes RedactedUsername and any other user (from the
+// whitelist, scene owners, etc.) becomes `user-1`, `user-2`, ...
+type redactor struct {
+	aliases map[string]string
+	others  int
+}
+
+func (r *redactor) alias(username string) string {
+	if r.aliases == nil {
+		r.aliases = map[string]string{}
+	}
+
+	if alias, ok := r.aliases[username]; ok {
+		return alias
+	}
+
+	r.others++
+	alias := fmt.Sprintf("user-%d", r.others)
+	r.aliases[username] = alias
+
+	return alias
+}
+
+// own remembers the username used to talk to the bridge
+func (r *redactor) own(username string) string {
+	if r.aliases == nil {
+		r.aliases = map[string]string{}
+	}
+
+	if _, ok := r.aliases[username]; !ok {
+		r.aliases[username] = RedactedUsername
+	}
+
+	return r.aliases[username]
+}
+
+// learnPath remembers the username in a request's path
+func (r *redactor) learnPath(path string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] != "api" || parts[1] == "" {
+		return
+	}
+
+	r.own(parts[1])
+}
+
+// redactAddress replaces the username in a command's address (`/api/<username>/groups/1/action` for example)
+func (r *redactor) redactAddress(address string) string {
+	parts := strings.SplitN(strings.TrimPrefix(address, "/"), "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return address
+	}
+
+	parts[1] = r.alias(parts[1])
+
+	return "/" + strings.Join(parts, "/")
+}
+
+// redact returns a copy of a JSON body with usernames replaced
+// Bodies that aren't JSON are kept as a JSON string.
+func (r *redactor) redact(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var v interface{}
+	if decoder.Decode(&v) != nil {
+		data, _ := json.Marshal(string(body))
+		return data
+	}
+
+	data, err := json.Marshal(r.walk(v))
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+func (r *redactor) walk(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if whitelist, ok := item.(map[string]interface{}); ok && key == "whitelist" {
+				// Aliases are handed out in a stable order so recordings don't change needlessly
+				usernames := make([]string, 0, len(whitelist))
+				for username := range whitelist {
+					usernames = append(usernames, username)
+				}
+				sort.Strings(usernames)
+
+				users := make(map[string]interface{}, len(whitelist))
+				for _, username := range usernames {
+					users[r.alias(username)] = r.walk(whitelist[username])
+				}
+				redacted[key] = users
+				continue
+			}
+
+			if username, ok := item.(string); ok && username != "" {
+				// Connect's response holds the username that'll be used from now on
+				if key == "username" {
+					redacted[key] = r.own(username)
+					continue
+				}
+
+				// The key used to encrypt entertainment streams
+				if key == "clientkey" {
+					redacted[key] = RedactedClientKey
+					continue
+				}
+
+				// Schedule and rule commands are sent to the API as a user
+				if key == "address" && strings.HasPrefix(username, "/api/") {
+					redacted[key] = r.redactAddress(username)
+					continue
+				}
+
+				if usernameKeys[key] {
+					redacted[key] = r.alias(username)
+					continue
+				}
+			}
+
+			redacted[key] = r.walk(item)
+		}
+
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = r.walk(item)
+		}
+
+		return redacted
+	case string:
+		if alias, ok := r.aliases[value]; ok {
+			return alias
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,246 @@
+// Package replay records HTTP interactions with a bridge and replays them later
+// A Recorder wraps a real transport and saves every request and response to a fixture file (with
+// usernames and client keys redacted). A Replayer serves the responses in a fixture without touching the
+// network. Both are http.RoundTrippers so they can be plugged into api.API's Client.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RedactedUsername replaces the username used to talk to the bridge in recorded interactions
+const RedactedUsername = "redacted"
+
+// RedactedClientKey replaces entertainment client keys in recorded interactions
+const RedactedClientKey = "00000000000000000000000000000000"
+
+// ErrNoInteraction is returned when a fixture holds no response for a request
+var ErrNoInteraction = errors.New("No recorded interaction matches request")
+
+// Request represents a recorded request
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Response represents a recorded response
+// JSON bodies are kept as is so fixtures are easy to read and edit. Anything else is kept in RawBody.
+type Response struct {
+	StatusCode  int             `json:"status"`
+	ContentType string          `json:"contenttype,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	RawBody     string          `json:"rawbody,omitempty"`
+}
+
+// Interaction represents a request and the response it received
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Fixture holds interactions in the order they happened
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Load reads a fixture from a file
+func Load(path string) (Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	var fixture Fixture
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("Failed to read fixture `%s`: %w", path, err)
+	}
+
+	return fixture, nil
+}
+
+// Save writes a fixture to a file
+func (fixture Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// redactPath replaces the username in a request's path (`/api/<username>/lights` for example)
+func redactPath(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] != "api" {
+		return path
+	}
+
+	parts[1] = RedactedUsername
+
+	return "/" + strings.Join(parts, "/")
+}
+
+// Recorder is an http.RoundTripper that records every interaction sent through it
+type Recorder struct {
+	// The transport requests are sent with (http.DefaultTransport if nil)
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	redactor     redactor
+}
+
+// NewRecorder creates a recorder sending requests with the given transport
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport}
+}
+
+// RoundTrip sends a request and records it along with its response
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := recorder.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.redactor.learnPath(req.URL.Path)
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			Path:   redactPath(req.URL.Path),
+			Body:   recorder.redactor.redact(reqBody),
+		},
+		Response: Response{
+			StatusCode:  resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+		},
+	}
+
+	if json.Valid(respBody) {
+		interaction.Response.Body = recorder.redactor.redact(respBody)
+	} else {
+		interaction.Response.RawBody = string(respBody)
+	}
+
+	recorder.interactions = append(recorder.interactions, interaction)
+
+	return resp, nil
+}
+
+// Fixture returns the interactions recorded so far
+func (recorder *Recorder) Fixture() Fixture {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return Fixture{Interactions: append([]Interaction{}, recorder.interactions...)}
+}
+
+// Save writes the interactions recorded so far to a file
+func (recorder *Recorder) Save(path string) error {
+	return recorder.Fixture().Save(path)
+}
+
+// Replayer is an http.RoundTripper that responds to requests with the responses in a fixture
+// Requests are matched by method and path (usernames are ignored). Matching interactions are replayed in
+// the order they were recorded and the last one is repeated once they've all been used.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer creates a replayer serving a fixture's responses
+func NewReplayer(fixture Fixture) *Replayer {
+	return &Replayer{
+		interactions: fixture.Interactions,
+		used:         make([]bool, len(fixture.Interactions)),
+	}
+}
+
+// RoundTrip responds to a request with its recorded response
+func (replayer *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	path := redactPath(req.URL.Path)
+
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	match := -1
+	for i, interaction := range replayer.interactions {
+		if interaction.Request.Method != req.Method || interaction.Request.Path != path {
+			continue
+		}
+
+		match = i
+		if !replayer.used[i] {
+			break
+		}
+	}
+
+	if match == -1 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, path)
+	}
+	replayer.used[match] = true
+
+	recorded := replayer.interactions[match].Response
+	body := []byte(recorded.RawBody)
+	if recorded.Body != nil {
+		body = recorded.Body
+	}
+
+	header := http.Header{}
+	if recorded.ContentType != "" {
+		header.Set("Content-Type", recorded.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/google/go-cmp/cmp"
+)
+
+func compact(t *testing.T, data json.RawMessage) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := json.Compact(&buf, data)
+	if err != nil {
+		t.Fatalf("Expected valid JSON but got `%s`", data)
+	}
+
+	return buf.String()
+}
+
+func sortLights(lights []api.Light) []api.Light {
+	sort.Slice(lights, func(i, j int) bool { return lights[i].ID < lights[j].ID })
+	for i := range lights {
+		lights[i].Bridge = nil
+	}
+
+	return lights
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	store := fakebridge.DefaultDatastore()
+	store.Config.Whitelist["0123456789abcdef"] = fakebridge.WhitelistEntry{Name: "Hue#Phone"}
+	scene := store.Scenes["relax"]
+	scene.Owner = "0123456789abcdef"
+	store.Scenes["relax"] = scene
+
+	server := fakebridge.NewServer(store)
+	defer server.Close()
+	server.Bridge.PressLinkButton()
+
+	recorder := NewRecorder(nil)
+	bridge := server.Endpoint(&api.API{Client: http.Client{Transport: recorder}})
+
+	username, err := bridge.Connect()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	recorded, err := bridge.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	_, err = bridge.GetScenes()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	resp, err := bridge.API.Client.Get("http://" + server.Addr().String() + "/api/" + username + "/config")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	resp.Body.Close()
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	err = recorder.Save(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	fixture, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	t.Run("Test usernames are redacted", func(t *testing.T) {
+		if len(fixture.Interactions) != 4 {
+			t.Fatalf("Expected 4 interactions but got %d", len(fixture.Interactions))
+		}
+
+		for _, interaction := range fixture.Interactions {
+			for _, text := range []string{interaction.Request.Path, string(interaction.Response.Body)} {
+				if strings.Contains(text, username) || strings.Contains(text, "0123456789abcdef") {
+					t.Errorf("Expected usernames to be redacted from `%s`", text)
+				}
+			}
+		}
+
+		expected := `[{"success":{"clientkey":"00000000000000000000000000000000","username":"redacted"}}]`
+		if body := compact(t, fixture.Interactions[0].Response.Body); body != expected {
+			t.Errorf("Expected `%s` but got `%s`", expected, body)
+		}
+
+		if path := fixture.Interactions[1].Request.Path; path != "/api/redacted/lights" {
+			t.Errorf("Expected `/api/redacted/lights` but got `%s`", path)
+		}
+
+		if body := compact(t, fixture.Interactions[2].Response.Body); !strings.Contains(body, `"owner":"user-1"`) {
+			t.Errorf("Expected scene owner to be an alias in `%s`", body)
+		}
+
+		if body := compact(t, fixture.Interactions[3].Response.Body); !strings.Contains(body, `"user-1":{"name":"Hue#Phone"}`) {
+			t.Errorf("Expected whitelist to use aliases in `%s`", body)
+		}
+	})
+
+	t.Run("Test replay", func(t *testing.T) {
+		replayed := api.Bridge{
+			API:      &api.API{Client: http.Client{Transport: NewReplayer(fixture)}},
+			IP:       []byte{192, 168, 1, 2},
+			Username: "someone-else",
+		}
+
+		for i := 0; i < 2; i++ {
+			lights, err := replayed.GetLights()
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+
+			if diff := cmp.Diff(sortLights(recorded), sortLights(lights)); diff != "" {
+				t.Errorf("Lights mismatch (-want +got):\n%s", diff)
+			}
+		}
+
+		_, err := replayed.GetGroups()
+		if !errors.Is(err, ErrNoInteraction) {
+			t.Errorf("Expected %v but got %v", ErrNoInteraction, err)
+		}
+	})
+}
+
+func TestReplayOrder(t *testing.T) {
+	fixture := Fixture{Interactions: []Interaction{
+		{
+			Request:  Request{Method: http.MethodGet, Path: "/api/redacted/lights"},
+			Response: Response{StatusCode: http.StatusServiceUnavailable, RawBody: "busy"},
+		},
+		{
+			Request:  Request{Method: http.MethodGet, Path: "/api/redacted/lights"},
+			Response: Response{StatusCode: http.StatusOK, ContentType: "application/json", Body: []byte(`{}`)},
+		},
+	}}
+
+	client := http.Client{Transport: NewReplayer(fixture)}
+	expected := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK}
+
+	for i, status := range expected {
+		resp, err := client.Get("http://127.0.0.1/api/user/lights")
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Errorf("Expected status %d for request %d but got %d", status, i, resp.StatusCode)
+		}
+	}
+}
+
+func TestRedactCommandAddress(t *testing.T) {
+	r := redactor{}
+	r.learnPath("/api/0123456789abcdef/schedules")
+
+	body := r.redact([]byte(`{
+		"1": {"command": {"address": "/api/0123456789abcdef/groups/1/action", "method": "PUT", "body": {"on": true}}},
+		"2": {"command": {"address": "/api/fedcba9876543210/lights/1/state", "method": "PUT", "body": {"on": false}}}
+	}`))
+
+	expected := `{"1":{"command":{"address":"/api/redacted/groups/1/action","body":{"on":true},"method":"PUT"}},` +
+		`"2":{"command":{"address":"/api/user-1/lights/1/state","body":{"on":false},"method":"PUT"}}}`
+	if actual := compact(t, body); actual != expected {
+		t.Errorf("Expected `%s` but got `%s`", expected, actual)
+	}
+}
@@ -0,0 +1,239 @@
+// Package wakeup simulates a sunrise to wake up to
+// A sunrise starts with a dim, deep red glow, warms up through orange and ends at full brightness in a
+// daylight white. It can be played from the client like any other effect or handed to the bridge as a set
+// of schedules, which keeps working while the client's machine is asleep. Either way the bridge does the
+// fading (with transition times) so only a handful of requests are sent.
+package wakeup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/effects"
+)
+
+// Defaults used for the zero values of Options
+const (
+	DefaultDuration   = 30 * time.Minute
+	DefaultEndKelvin  = 5500
+	DefaultBrightness = api.MaximumBrightness
+)
+
+// DefaultStartColor is the deep red a sunrise starts with
+var DefaultStartColor = api.CIECoord{0.675, 0.322}
+
+// The shortest and longest sunrises (each third of a sunrise is a single transition)
+const (
+	MinimumDuration = time.Minute
+	MaximumDuration = 3 * time.Hour
+)
+
+// ScheduleDescription identifies the schedules created by Schedule
+const ScheduleDescription = "hugh wake-up routine"
+
+// The color temperatures a sunrise passes through (in Kelvin)
+const (
+	sunriseOrange = 2000
+	sunriseWarm   = 3000
+)
+
+// settleTime is how long the lights are given to switch to the start color before fading starts
+const settleTime = time.Second
+
+// ErrInvalidDuration is returned for sunrises shorter than MinimumDuration or longer than MaximumDuration
+var ErrInvalidDuration = fmt.Errorf("Sunrise must last between %v and %v", MinimumDuration, MaximumDuration)
+
+// Options configures a sunrise
+type Options struct {
+	// How long it takes to go from off to full brightness (DefaultDuration if zero)
+	Duration time.Duration
+
+	// The color lights start with (DefaultStartColor if zero)
+	StartColor api.CIECoord
+
+	// The color temperature in Kelvin lights end with (DefaultEndKelvin if zero)
+	EndKelvin uint
+
+	// The brightness lights end with (DefaultBrightness if zero)
+	Brightness uint8
+}
+
+// withDefaults fills in any unset options
+func (options Options) withDefaults() Options {
+	if options.Duration == 0 {
+		options.Duration = DefaultDuration
+	}
+
+	if options.StartColor == (api.CIECoord{}) {
+		options.StartColor = DefaultStartColor
+	}
+
+	if options.EndKelvin == 0 {
+		options.EndKelvin = DefaultEndKelvin
+	}
+
+	if options.Brightness == 0 {
+		options.Brightness = DefaultBrightness
+	}
+
+	return options
+}
+
+// Step is a state change sent at some point during a sunrise
+type Step struct {
+	// How long after the start of the sunrise the change is sent
+	Offset time.Duration
+
+	// The change (its transition time is the time until the next step)
+	Update api.LightStateUpdate
+}
+
+// Plan returns the state changes making up a sunrise
+// Offsets are whole seconds so steps can be scheduled on a bridge.
+func Plan(options Options) ([]Step, error) {
+	options = options.withDefaults()
+
+	if options.Duration < MinimumDuration || options.Duration > MaximumDuration {
+		return nil, ErrInvalidDuration
+	}
+
+	phase := (options.Duration / 3).Round(time.Second)
+	scale := func(fraction float64) *uint8 {
+		brightness := uint8(float64(options.Brightness)*fraction + 0.5)
+		if brightness < api.MinimumBrightness {
+			brightness = api.MinimumBrightness
+		}
+
+		return &brightness
+	}
+	temperature := func(kelvin uint) *uint16 {
+		mired := uint16(api.DefaultTemperatureRange.ClampKelvin(kelvin))
+		return &mired
+	}
+	transition := func(d time.Duration) *uint16 {
+		units := api.TransitionTimeUnits(d)
+		return &units
+	}
+
+	on := true
+	minimum := uint8(api.MinimumBrightness)
+	start := options.StartColor
+	orange := api.KelvinToCIE(sunriseOrange)
+
+	return []Step{
+		{
+			Offset: 0,
+			Update: api.LightStateUpdate{On: &on, Brightness: &minimum, CIECoords: &start, TransitionTime: transition(0)},
+		},
+		{
+			Offset: settleTime,
+			Update: api.LightStateUpdate{Brightness: scale(0.15), CIECoords: &orange, TransitionTime: transition(phase - settleTime)},
+		},
+		{
+			Offset: phase,
+			Update: api.LightStateUpdate{Brightness: scale(0.5), Temperature: temperature(sunriseWarm), TransitionTime: transition(phase)},
+		},
+		{
+			Offset: 2 * phase,
+			Update: api.LightStateUpdate{Brightness: scale(1), Temperature: temperature(options.EndKelvin), TransitionTime: transition(phase)},
+		},
+	}, nil
+}
+
+// Effect plays a sunrise from the client
+// The target (usually an *api.Group) is switched on and the effect returns once the sunrise is over.
+func Effect(options Options) (effects.Effect, error) {
+	steps, err := Plan(options)
+	if err != nil {
+		return nil, err
+	}
+
+	fades := make([]effects.Effect, len(steps))
+	for i, step := range steps {
+		// Waiting for each transition to finish brings the next step right on time
+		fades[i] = effects.Fade(step.Update, time.Duration(*step.Update.TransitionTime)*api.TransitionTimeUnit)
+	}
+
+	// The start color is sent without a transition so give the lights a moment before fading
+	fades[0] = effects.Sequence(fades[0], effects.Wait(settleTime))
+
+	return effects.Sequence(fades...), nil
+}
+
+// Schedule creates bridge schedules that play a sunrise on a group, finishing at the wake up time
+// The bridge interprets schedule times in its own timezone so wake should be in the bridge's location. The
+// IDs of the created schedules are returned. Schedules delete themselves once they've run and can be
+// removed before then with Cancel.
+func Schedule(ctx context.Context, group *api.Group, wake time.Time, options Options) ([]string, error) {
+	steps, err := Plan(options)
+	if err != nil {
+		return nil, err
+	}
+
+	options = options.withDefaults()
+	start := wake.Add(-options.Duration).Truncate(time.Second)
+	if !start.After(time.Now()) {
+		return nil, fmt.Errorf("Sunrise would have started at %v", start)
+	}
+
+	ids := []string{}
+	for i, step := range steps {
+		id, err := scheduleStep(ctx, group, start.Add(step.Offset), step.Update, fmt.Sprintf("Wake up %d/%d", i+1, len(steps)))
+		if err != nil {
+			// Don't leave half a sunrise behind
+			for _, id := range ids {
+				schedule := api.Schedule{ID: id, Bridge: group.Bridge}
+				schedule.Delete(context.Background())
+			}
+
+			return nil, fmt.Errorf("Failed to schedule sunrise: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// scheduleStep creates a schedule sending a state change to a group at a certain time
+func scheduleStep(ctx context.Context, group *api.Group, at time.Time, update api.LightStateUpdate, name string) (string, error) {
+	command, err := group.ActionCommand(update)
+	if err != nil {
+		return "", err
+	}
+
+	return group.Bridge.CreateSchedule(ctx, api.Schedule{
+		Name:        name,
+		Description: ScheduleDescription,
+		Command:     command,
+		LocalTime:   api.ScheduleTime(at),
+		Status:      api.ScheduleStatusEnabled,
+		AutoDelete:  true,
+	})
+}
+
+// Cancel deletes every sunrise schedule on a bridge that hasn't run yet
+func Cancel(ctx context.Context, bridge *api.Bridge) error {
+	schedules, err := bridge.GetSchedules()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, schedule := range schedules {
+		if schedule.Description != ScheduleDescription {
+			continue
+		}
+
+		err := schedule.Delete(ctx)
+		// Schedules may run (and delete themselves) in the meantime
+		if err != nil && !errors.Is(err, api.ErrResourceNotAvailable) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,169 @@
+package wakeup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alejandro-angulo/hugh/pkg/api"
+	"github.com/alejandro-angulo/hugh/pkg/fakebridge"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     Options
+		offsets     []time.Duration
+		transitions []uint16
+		brightness  []uint8
+		err         error
+	}{
+		{
+			name:        "Test defaults",
+			options:     Options{},
+			offsets:     []time.Duration{0, time.Second, 10 * time.Minute, 20 * time.Minute},
+			transitions: []uint16{0, 5990, 6000, 6000},
+			brightness:  []uint8{1, 38, 127, 254},
+		},
+		{
+			name:        "Test custom duration and brightness",
+			options:     Options{Duration: 3 * time.Minute, Brightness: 100},
+			offsets:     []time.Duration{0, time.Second, time.Minute, 2 * time.Minute},
+			transitions: []uint16{0, 590, 600, 600},
+			brightness:  []uint8{1, 15, 50, 100},
+		},
+		{
+			name:    "Test too short",
+			options: Options{Duration: time.Second},
+			err:     ErrInvalidDuration,
+		},
+		{
+			name:    "Test too long",
+			options: Options{Duration: 4 * time.Hour},
+			err:     ErrInvalidDuration,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := Plan(tt.options)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected %v but got %v", tt.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			offsets := []time.Duration{}
+			transitions := []uint16{}
+			brightness := []uint8{}
+			for _, step := range steps {
+				offsets = append(offsets, step.Offset)
+				transitions = append(transitions, *step.Update.TransitionTime)
+				brightness = append(brightness, *step.Update.Brightness)
+
+				if err := step.Update.Validate(nil); err != nil {
+					t.Errorf("Expected a valid update but got %v", err)
+				}
+			}
+
+			if diff := cmp.Diff(tt.offsets, offsets); diff != "" {
+				t.Errorf("Offsets mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.transitions, transitions); diff != "" {
+				t.Errorf("Transitions mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.brightness, brightness); diff != "" {
+				t.Errorf("Brightness mismatch (-want +got):\n%s", diff)
+			}
+
+			first, last := steps[0].Update, steps[len(steps)-1].Update
+			if !*first.On || *first.CIECoords != DefaultStartColor {
+				t.Errorf("Expected the sunrise to start with a red light but got %+v", first)
+			}
+
+			if *last.Temperature != uint16(api.KelvinToMired(DefaultEndKelvin)) {
+				t.Errorf("Expected the sunrise to end at %dK but got %d mireds", DefaultEndKelvin, *last.Temperature)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	server := fakebridge.NewServer(fakebridge.DefaultDatastore())
+	defer server.Close()
+
+	bridge := server.Endpoint(&api.API{})
+	bridge.Username = server.Bridge.Register("hugh#test")
+
+	groups, err := bridge.GetGroups()
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	group := groups[0]
+
+	t.Run("Test sunrise in the past", func(t *testing.T) {
+		_, err := Schedule(context.Background(), &group, time.Now(), Options{})
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Test scheduled sunrise", func(t *testing.T) {
+		wake := time.Date(2099, 1, 2, 7, 0, 0, 0, time.Local)
+
+		ids, err := Schedule(context.Background(), &group, wake, Options{})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		schedules := server.Bridge.Datastore().Schedules
+		times := []string{}
+		for _, id := range ids {
+			schedule := schedules[id]
+			times = append(times, schedule.LocalTime)
+
+			if schedule.Command.Address != "/api/"+bridge.Username+"/groups/"+group.ID+"/action" || !schedule.AutoDelete {
+				t.Errorf("Unexpected schedule %+v", schedule)
+			}
+		}
+
+		expected := []string{"2099-01-02T06:30:00", "2099-01-02T06:30:01", "2099-01-02T06:40:00", "2099-01-02T06:50:00"}
+		if diff := cmp.Diff(expected, times); diff != "" {
+			t.Errorf("Times mismatch (-want +got):\n%s", diff)
+		}
+
+		var update api.LightStateUpdate
+		err = json.Unmarshal(schedules[ids[0]].Command.Body, &update)
+		if err != nil || update.On == nil || !*update.On {
+			t.Errorf("Expected the first schedule to turn the lights on but got `%s`", schedules[ids[0]].Command.Body)
+		}
+	})
+
+	t.Run("Test cancel", func(t *testing.T) {
+		_, err := bridge.CreateSchedule(context.Background(), api.Schedule{
+			Name:      "Unrelated",
+			Command:   api.ScheduleCommand{Address: "/api/" + bridge.Username + "/groups/1/action", Method: "PUT", Body: json.RawMessage(`{"on":false}`)},
+			LocalTime: "2099-01-01T00:00:00",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		err = Cancel(context.Background(), bridge)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+
+		schedules := server.Bridge.Datastore().Schedules
+		if len(schedules) != 1 {
+			t.Errorf("Expected only the unrelated schedule to be left but got %+v", schedules)
+		}
+	})
+}